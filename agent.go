@@ -0,0 +1,255 @@
+package gotask
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAgentRunning    error = errors.New("agent already running")
+	ErrAgentNotRunning error = errors.New("agent not running")
+	ErrAgentNoHandler  error = errors.New("agent has no handler for task kind")
+)
+
+// AgentHandler Runs task of one kind on an agent and returns its serialized result
+type AgentHandler func(job *AgentJob) ([]byte, error)
+
+// AgentJob Task presently run by an agent, passed to the handler of its kind
+type AgentJob struct {
+	mu       sync.Mutex
+	spec     TaskSpec
+	progress Progress
+	once     sync.Once
+	done     chan struct{}
+}
+
+// GetName Returns name of task
+func (j *AgentJob) GetName() string {
+	return j.spec.Name
+}
+
+// GetPayload Returns serialized argument of task
+func (j *AgentJob) GetPayload() []byte {
+	return j.spec.Payload
+}
+
+// SetProgress Sets progress of task in percent from 0 to 100, which is sent to the broker with the next heartbeat
+func (j *AgentJob) SetProgress(progress Progress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if progress > MaxProgress {
+		progress = MaxProgress
+	}
+	j.progress = progress
+}
+
+// GetProgress Returns progress of task
+func (j *AgentJob) GetProgress() Progress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
+
+// Done Returns channel which is closed once the task is not needed anymore, e.g. as the agent was stopped or the
+// worker waiting for the task was stopped. A result returned afterwards is dropped.
+func (j *AgentJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// cancel Closes done channel, only the first call has an effect
+func (j *AgentJob) cancel() {
+	j.once.Do(func() {
+		close(j.done)
+	})
+}
+
+// Agent Polls tasks from a broker and runs them with the handler registered for their kind, one task at a time
+type Agent struct {
+	mu        sync.Mutex
+	name      string
+	broker    Broker
+	handlers  map[string]AgentHandler
+	pollWait  time.Duration // maximum time of one poll
+	heartbeat time.Duration // interval of progress reports while running a task
+	running   bool
+	current   *AgentJob     // task presently run, nil if polling
+	quit      chan struct{} // closed by Stop
+	stopped   chan struct{} // closed once the poll loop exited
+}
+
+// NewAgent Factory method for creating a new agent polling tasks from broker under a unique name
+func NewAgent(name string, broker Broker) *Agent {
+	agent := Agent{
+		name:      name,
+		broker:    broker,
+		handlers:  make(map[string]AgentHandler),
+		pollWait:  time.Second,
+		heartbeat: time.Second,
+	}
+	return &agent
+}
+
+// Handle Registers handler for tasks of kind, returns agent for chaining
+func (a *Agent) Handle(kind string, handler AgentHandler) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handlers[kind] = handler
+	return a
+}
+
+// SetHeartbeat Sets interval in which progress of a running task is reported to the broker, returns agent for chaining
+// Note: The interval must be well below the agent timeout of the broker, otherwise the agent is dropped while running a task
+func (a *Agent) SetHeartbeat(heartbeat time.Duration) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if heartbeat < time.Millisecond {
+		heartbeat = time.Millisecond
+	}
+	a.heartbeat = heartbeat
+	a.pollWait = heartbeat
+	return a
+}
+
+// GetName Returns agent name
+func (a *Agent) GetName() string {
+	return a.name
+}
+
+// GetCurrentTaskName Returns name of task presently run by the agent
+func (a *Agent) GetCurrentTaskName() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.running || a.current == nil {
+		return "", ErrAgentNotRunning
+	}
+	return a.current.GetName(), nil
+}
+
+// Start Registers agent at broker and starts polling tasks in background
+func (a *Agent) Start() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.running {
+		return ErrAgentRunning
+	}
+	if err := a.broker.Register(a.name); err != nil {
+		return err
+	}
+	a.running = true
+	a.quit = make(chan struct{})
+	a.stopped = make(chan struct{})
+	go a.loop(a.quit, a.stopped)
+	return nil
+}
+
+// Stop Stops polling and unregisters agent, a task still running is canceled and re-queued by the broker
+func (a *Agent) Stop() error {
+	a.mu.Lock()
+	if !a.running {
+		a.mu.Unlock()
+		return ErrAgentNotRunning
+	}
+	a.running = false
+	close(a.quit)
+	current, stopped := a.current, a.stopped
+	a.mu.Unlock()
+
+	if current != nil {
+		current.cancel()
+	}
+	_ = a.broker.Unregister(a.name)
+	<-stopped
+	return nil
+}
+
+// loop Polls and runs tasks until agent is stopped
+func (a *Agent) loop(quit chan struct{}, stopped chan struct{}) {
+	defer close(stopped)
+	for {
+		a.mu.Lock()
+		pollWait := a.pollWait
+		a.mu.Unlock()
+
+		spec, err := a.broker.Poll(a.name, pollWait)
+		select {
+		case <-quit:
+			return
+		default:
+		}
+		if err != nil {
+			// the broker dropped the agent or can not be reached, so the agent registers again after a pause
+			select {
+			case <-quit:
+				return
+			case <-time.After(pollWait):
+			}
+			_ = a.broker.Register(a.name)
+			continue
+		}
+		if spec != nil {
+			a.run(*spec, quit)
+		}
+	}
+}
+
+// run Runs task with handler of its kind and reports progress and result to the broker
+func (a *Agent) run(spec TaskSpec, quit chan struct{}) {
+	job := &AgentJob{spec: spec, done: make(chan struct{})}
+	a.mu.Lock()
+	select {
+	case <-quit:
+		a.mu.Unlock()
+		return
+	default:
+	}
+	a.current = job
+	handler, ok := a.handlers[spec.Kind]
+	heartbeat := a.heartbeat
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.current = nil
+		a.mu.Unlock()
+	}()
+
+	if !ok {
+		err := fmt.Errorf("%w: '%s'", ErrAgentNoHandler, spec.Kind)
+		_ = a.broker.Report(a.name, TaskReport{ID: spec.ID, Done: true, Error: err.Error()})
+		return
+	}
+
+	var result []byte
+	var err error
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		result, err = handler(job)
+	}()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-finished:
+			select {
+			case <-job.Done():
+				return // task was canceled or re-queued, so the result is not needed anymore
+			default:
+			}
+			report := TaskReport{ID: spec.ID, Done: true, Result: result}
+			if err != nil {
+				report.Error = err.Error()
+			}
+			_ = a.broker.Report(a.name, report)
+			return
+		case <-ticker.C:
+			// the task is given up if the broker does not know it anymore or can not be reached, in both cases the
+			// broker re-queues it for another agent
+			if err := a.broker.Report(a.name, TaskReport{ID: spec.ID, Progress: job.GetProgress()}); err != nil {
+				job.cancel()
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package gotask
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrBrokerAgentUnknown error = errors.New("agent not registered at broker")
+	ErrBrokerTaskUnknown  error = errors.New("task not assigned to agent, it was canceled or re-queued")
+	ErrBrokerTaskFailed   error = errors.New("remote task failed")
+)
+
+// Broker Hands serializable task definitions to agents and collects their progress and results
+// Agents only talk to a broker over this interface, so they can run in the same process or on other machines.
+type Broker interface {
+	Register(agent string) error                              // registers agent, registering again refreshes it
+	Unregister(agent string) error                            // removes agent, tasks assigned to it are re-queued
+	Poll(agent string, wait time.Duration) (*TaskSpec, error) // returns next task for agent, nil if none arrived within wait
+	Report(agent string, report TaskReport) error             // reports progress or result of task assigned to agent
+}
+
+// TaskSpec Serializable definition of a task run by an agent
+type TaskSpec struct {
+	ID      string // unique id assigned by broker
+	Name    string // name of task
+	Kind    string // selects the handler of the agent running the task
+	Payload []byte // argument of task, e.g. encoded as JSON
+}
+
+// TaskReport Progress or result of a task sent from an agent to the broker
+// Reports also serve as heartbeat of the agent while it runs a long task.
+type TaskReport struct {
+	ID       string   // id of task
+	Progress Progress // progress of task in percent from 0 to 100
+	Done     bool     // task completed, Result and Error are set
+	Result   []byte   // result of task
+	Error    string   // error message of failed task, empty if successful
+}
+
+// MemoryBroker In-memory broker queueing tasks of remote tasks for agents
+// Agents in the same process use it directly, agents on other machines connect over Serve and DialBroker.
+// An agent which did not poll or report within the agent timeout is dropped and its tasks are re-queued.
+type MemoryBroker struct {
+	mu           sync.Mutex
+	agentTimeout time.Duration
+	agents       map[string]time.Time  // registered agents and the time they were last seen
+	queue        []*brokerJob          // jobs waiting for an agent in submit order, re-queued jobs first
+	jobs         map[string]*brokerJob // all jobs not completed yet by id
+	wake         chan struct{}         // closed and replaced once the queue grows or agents are removed
+	nextID       uint64
+}
+
+// brokerJob Task submitted to the broker and its present assignment
+type brokerJob struct {
+	spec     TaskSpec
+	seq      uint64 // submit order
+	agent    string // agent running the job, empty while queued
+	progress Progress
+	result   []byte
+	err      error
+	done     chan struct{} // closed once job completed
+}
+
+// NewMemoryBroker Factory method for creating a new in-memory broker with an agent timeout of ten seconds
+func NewMemoryBroker() *MemoryBroker {
+	broker := MemoryBroker{
+		agentTimeout: 10 * time.Second,
+		agents:       make(map[string]time.Time),
+		jobs:         make(map[string]*brokerJob),
+		wake:         make(chan struct{}),
+	}
+	return &broker
+}
+
+// SetAgentTimeout Sets time after which an agent which neither polled nor reported is dropped, minimum is one millisecond
+func (m *MemoryBroker) SetAgentTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if timeout < time.Millisecond {
+		timeout = time.Millisecond
+	}
+	m.agentTimeout = timeout
+}
+
+// GetAgents Returns names of all registered agents
+func (m *MemoryBroker) GetAgents() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	agents := make([]string, 0, len(m.agents))
+	for agent := range m.agents {
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// GetQueued Returns amount of tasks waiting for an agent
+func (m *MemoryBroker) GetQueued() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queue)
+}
+
+// Register Registers agent, registering again refreshes it
+func (m *MemoryBroker) Register(agent string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agents[agent] = time.Now()
+	return nil
+}
+
+// Unregister Removes agent, tasks assigned to it are re-queued
+func (m *MemoryBroker) Unregister(agent string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.agents[agent]; !ok {
+		return ErrBrokerAgentUnknown
+	}
+	m.drop(agent)
+	return nil
+}
+
+// Poll Returns next task for agent and assigns it to the agent, waits at most wait for a task to arrive
+// Returns nil if no task arrived within wait. Wait is limited to half the agent timeout, so polling keeps the agent alive.
+func (m *MemoryBroker) Poll(agent string, wait time.Duration) (*TaskSpec, error) {
+	m.mu.Lock()
+	if wait > m.agentTimeout/2 {
+		wait = m.agentTimeout / 2
+	}
+	m.mu.Unlock()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		m.mu.Lock()
+		m.expire()
+		if _, ok := m.agents[agent]; !ok {
+			m.mu.Unlock()
+			return nil, ErrBrokerAgentUnknown
+		}
+		m.agents[agent] = time.Now()
+		if len(m.queue) > 0 {
+			job := m.queue[0]
+			m.queue[0] = nil
+			m.queue = m.queue[1:]
+			job.agent = agent
+			m.mu.Unlock()
+			spec := job.spec
+			return &spec, nil
+		}
+		wake := m.wake
+		m.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-timer.C:
+			return nil, nil
+		}
+	}
+}
+
+// Report Records progress or result of task assigned to agent
+// Returns ErrBrokerTaskUnknown if the task is not assigned to the agent anymore, so the agent can give it up.
+func (m *MemoryBroker) Report(agent string, report TaskReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	if _, ok := m.agents[agent]; !ok {
+		return ErrBrokerAgentUnknown
+	}
+	m.agents[agent] = time.Now()
+	job, ok := m.jobs[report.ID]
+	if !ok || job.agent != agent {
+		return ErrBrokerTaskUnknown
+	}
+	if !report.Done {
+		job.progress = report.Progress
+		return nil
+	}
+	job.progress = MaxProgress
+	job.result = report.Result
+	if report.Error != "" {
+		job.progress = MinProgress
+		job.err = errors.New(report.Error)
+	}
+	delete(m.jobs, job.spec.ID)
+	close(job.done)
+	return nil
+}
+
+// submit Queues task for the next polling agent
+func (m *MemoryBroker) submit(spec TaskSpec) *brokerJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	spec.ID = strconv.FormatUint(m.nextID, 10)
+	job := &brokerJob{spec: spec, seq: m.nextID, done: make(chan struct{})}
+	m.jobs[spec.ID] = job
+	m.queue = append(m.queue, job)
+	m.notify()
+	return job
+}
+
+// cancel Removes job which is not needed anymore, the agent running it is told on its next report
+func (m *MemoryBroker) cancel(job *brokerJob) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, job.spec.ID)
+	for idx, queued := range m.queue {
+		if queued == job {
+			m.queue = append(m.queue[:idx], m.queue[idx+1:]...)
+			break
+		}
+	}
+}
+
+// status Returns progress and agent of job
+func (m *MemoryBroker) status(job *brokerJob) (Progress, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return job.progress, job.agent
+}
+
+// result Returns result and error of completed job, the error names the agent which ran the job
+func (m *MemoryBroker) result(job *brokerJob) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if job.err != nil {
+		return job.result, fmt.Errorf("%w: agent '%s': %v", ErrBrokerTaskFailed, job.agent, job.err)
+	}
+	return job.result, nil
+}
+
+// expire Drops all agents which were not seen within the agent timeout, caller must hold the lock
+func (m *MemoryBroker) expire() {
+	for agent, seen := range m.agents {
+		if time.Since(seen) > m.agentTimeout {
+			m.drop(agent)
+		}
+	}
+}
+
+// drop Removes agent and re-queues its jobs in front of the queue, caller must hold the lock
+func (m *MemoryBroker) drop(agent string) {
+	delete(m.agents, agent)
+	var requeued []*brokerJob
+	for _, job := range m.jobs {
+		if job.agent == agent {
+			job.agent = ""
+			job.progress = MinProgress
+			requeued = append(requeued, job)
+		}
+	}
+	sort.Slice(requeued, func(i, j int) bool {
+		return requeued[i].seq < requeued[j].seq
+	})
+	m.queue = append(requeued, m.queue...)
+	m.notify()
+}
+
+// notify Wakes up all polling agents, caller must hold the lock
+func (m *MemoryBroker) notify() {
+	close(m.wake)
+	m.wake = make(chan struct{})
+}
+
+// sweep Drops all agents which were not seen within the agent timeout and returns the timeout
+func (m *MemoryBroker) sweep() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	return m.agentTimeout
+}
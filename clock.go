@@ -0,0 +1,25 @@
+package gotask
+
+import "time"
+
+// Clock Source of time used by time dependent parts of the package, can be replaced to test them deterministically
+type Clock interface {
+	Now() time.Time                         // returns current time
+	After(d time.Duration) <-chan time.Time // returns channel which receives the current time once duration elapsed
+}
+
+// SystemClock Clock implementation based on the system time, used by default
+var SystemClock Clock = systemClock{}
+
+// systemClock Clock implementation forwarding to the time package
+type systemClock struct{}
+
+// Now Returns current system time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// After Waits for duration to elapse and then sends current time on returned channel
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
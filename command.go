@@ -0,0 +1,357 @@
+package gotask
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrCommandFailed error = errors.New("command exited with non-zero code")
+)
+
+// CommandError Error of a command which exited with a non-zero exit code
+type CommandError struct {
+	Command  string // command line
+	ExitCode int    // exit code of command, -1 if it was killed by a signal
+	Stderr   string // last line written to stderr
+}
+
+// Error Returns error message containing command, exit code and last line of stderr
+func (e *CommandError) Error() string {
+	if e.Stderr == "" {
+		return fmt.Sprintf("command '%s' exited with code %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("command '%s' exited with code %d: %s", e.Command, e.ExitCode, e.Stderr)
+}
+
+// Is Reports that every command error is an ErrCommandFailed
+func (e *CommandError) Is(target error) bool {
+	return target == ErrCommandFailed
+}
+
+// CommandTask Task running an external program
+// Stdout and stderr are captured, a non-zero exit code fails the task. Once the worker is stopped or times out the
+// program receives SIGTERM and is killed if it did not exit within the grace period.
+type CommandTask struct {
+	mu       sync.Mutex
+	name     string
+	desc     string
+	weight   Weight
+	command  string
+	args     []string
+	env      []string // additional environment variables in the form key=value
+	dir      string
+	stdin    io.Reader
+	grace    time.Duration                      // time between SIGTERM and SIGKILL
+	parser   func(line string) (Progress, bool) // parses progress from output line, nil if not used
+	state    State
+	progress Progress
+	stdout   bytes.Buffer
+	stderr   bytes.Buffer
+	exitCode int
+	err      error
+	handle   *Handle
+}
+
+// NewCommandTask Factory method for creating a new task running command with args
+// The grace period between SIGTERM and SIGKILL is five seconds by default.
+func NewCommandTask(name string, weight Weight, desc string, command string, args ...string) *CommandTask {
+	task := CommandTask{
+		name:    name,
+		desc:    desc,
+		weight:  weight,
+		command: command,
+		args:    args,
+		grace:   5 * time.Second,
+		state:   Waiting,
+	}
+	return &task
+}
+
+// SetEnv Sets environment variables in the form key=value added to the environment of the process, returns task for chaining
+func (c *CommandTask) SetEnv(env ...string) *CommandTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.env = env
+	return c
+}
+
+// SetDir Sets working directory of command, returns task for chaining
+func (c *CommandTask) SetDir(dir string) *CommandTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dir = dir
+	return c
+}
+
+// SetStdin Sets reader passed to command as stdin, returns task for chaining
+// Note: A reader can only be consumed once, so a task rerun after Reset needs a new reader
+func (c *CommandTask) SetStdin(stdin io.Reader) *CommandTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stdin = stdin
+	return c
+}
+
+// SetGracePeriod Sets time a stopped command has to exit after SIGTERM before it is killed, returns task for chaining
+func (c *CommandTask) SetGracePeriod(grace time.Duration) *CommandTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.grace = grace
+	return c
+}
+
+// SetProgressParser Sets function parsing the progress from lines written to stdout or stderr, returns task for chaining
+// Lines the parser does not accept are ignored. See PercentProgress for a parser of lines like "42%".
+func (c *CommandTask) SetProgressParser(parser func(line string) (Progress, bool)) *CommandTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.parser = parser
+	return c
+}
+
+// percentPattern Matches percentages like "42%" or "42.5 %"
+var percentPattern = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*%`)
+
+// PercentProgress Progress parser taking the last percentage of a line, e.g. "copied 42%"
+func PercentProgress(line string) (Progress, bool) {
+	matches := percentPattern.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return MinProgress, false
+	}
+	percent, err := strconv.ParseFloat(matches[len(matches)-1][1], 32)
+	if err != nil || percent > float64(MaxProgress) {
+		return MinProgress, false
+	}
+	return Progress(percent), true
+}
+
+// Run Runs command and waits until it exited, this is called by worker
+func (c *CommandTask) Run() {
+	c.mu.Lock()
+	c.state = Running
+	c.progress = MinProgress
+	c.stdout.Reset()
+	c.stderr.Reset()
+	c.exitCode = 0
+	c.err = nil
+	cmd := exec.Command(c.command, c.args...)
+	cmd.Dir = c.dir
+	cmd.Stdin = c.stdin
+	setProcessGroup(cmd)
+	if len(c.env) > 0 {
+		cmd.Env = append(os.Environ(), c.env...)
+	}
+	handle, grace := c.handle, c.grace
+	var tee io.Writer = ioutil.Discard
+	if handle != nil {
+		tee = handle.GetOutput()
+	}
+	cmd.Stdout = &commandOutput{task: c, buf: &c.stdout, tee: tee}
+	cmd.Stderr = &commandOutput{task: c, buf: &c.stderr, tee: tee}
+	c.mu.Unlock()
+
+	var done <-chan struct{}
+	if handle != nil {
+		done = handle.Done()
+	}
+
+	err := cmd.Start()
+	canceled := false
+	if err == nil {
+		exited := make(chan error, 1)
+		go func() {
+			exited <- cmd.Wait()
+		}()
+		select {
+		case err = <-exited:
+		case <-done:
+			canceled = true
+			err = terminate(cmd, exited, grace)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var exitErr *exec.ExitError
+	switch {
+	case canceled:
+		c.state = Canceled
+		c.err = handle.Err()
+	case errors.As(err, &exitErr):
+		c.state = Failed
+		c.exitCode = exitErr.ExitCode()
+		c.err = &CommandError{Command: c.commandLine(), ExitCode: c.exitCode, Stderr: lastLine(c.stderr.String())}
+	case err != nil:
+		c.state = Failed
+		c.exitCode = -1
+		c.err = err
+	default:
+		c.state = Finished
+		c.progress = MaxProgress
+	}
+}
+
+// terminate Sends SIGTERM to command and its children and kills them if they did not exit within grace period
+// The children are signaled as well, as they would keep the output pipes open and so block waiting for the command.
+// Note: Platforms without SIGTERM kill the command at once, platforms without process groups signal the command only
+func terminate(cmd *exec.Cmd, exited chan error, grace time.Duration) error {
+	if err := signalGroup(cmd, syscall.SIGTERM); err != nil {
+		_ = signalGroup(cmd, syscall.SIGKILL)
+		return <-exited
+	}
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case err := <-exited:
+		return err
+	case <-timer.C:
+		_ = signalGroup(cmd, syscall.SIGKILL)
+		return <-exited
+	}
+}
+
+// commandOutput Captures output of command, passes it on to the task output and parses progress lines
+type commandOutput struct {
+	task    *CommandTask
+	buf     *bytes.Buffer
+	tee     io.Writer // output sink of the task run, written without holding the task lock
+	partial []byte    // begin of line not terminated yet
+}
+
+// Write Stores output and passes complete lines to progress parser
+func (o *commandOutput) Write(p []byte) (int, error) {
+	o.capture(p)
+	_, _ = o.tee.Write(p)
+	return len(p), nil
+}
+
+// capture Stores output and passes complete lines to progress parser
+func (o *commandOutput) capture(p []byte) {
+	o.task.mu.Lock()
+	defer o.task.mu.Unlock()
+	o.buf.Write(p)
+	if o.task.parser == nil {
+		return
+	}
+
+	o.partial = append(o.partial, p...)
+	for {
+		// carriage returns are used by many programs to redraw a progress line in place
+		idx := bytes.IndexAny(o.partial, "\r\n")
+		if idx < 0 {
+			break
+		}
+		if progress, ok := o.task.parser(string(o.partial[:idx])); ok {
+			o.task.progress = progress
+		}
+		o.partial = o.partial[idx+1:]
+	}
+}
+
+// commandLine Returns command with args for messages, caller must hold the lock
+func (c *CommandTask) commandLine() string {
+	return strings.Join(append([]string{c.command}, c.args...), " ")
+}
+
+// lastLine Returns last non empty line of output
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// bind Stores handle of worker which is about to run the task
+func (c *CommandTask) bind(h *Handle) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handle = h
+}
+
+// GetName Returns task name
+func (c *CommandTask) GetName() string {
+	return c.name
+}
+
+// GetState Returns task state
+func (c *CommandTask) GetState() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// GetProgress Returns task progress, which is only updated during the run if a progress parser is set
+func (c *CommandTask) GetProgress() Progress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progress
+}
+
+// GetWeight Returns task weight
+func (c *CommandTask) GetWeight() Weight {
+	return c.weight
+}
+
+// GetDesc Returns task description
+func (c *CommandTask) GetDesc() string {
+	return c.desc
+}
+
+// GetWorkLoad Returns task workload (progress times weight)
+func (c *CommandTask) GetWorkLoad() int {
+	return int(float64(c.GetProgress()) * float64(c.weight) / float64(MaxProgress))
+}
+
+// GetStdout Returns output written to stdout in the present or last run
+func (c *CommandTask) GetStdout() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stdout.String()
+}
+
+// GetStderr Returns output written to stderr in the present or last run
+func (c *CommandTask) GetStderr() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stderr.String()
+}
+
+// GetExitCode Returns exit code of last run, -1 if the command could not be started or was killed by a signal
+func (c *CommandTask) GetExitCode() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.exitCode
+}
+
+// GetError Returns CommandError, start error or cancel reason of the worker of last run, nil if successful
+func (c *CommandTask) GetError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Reset Resets task to start state
+func (c *CommandTask) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == Running {
+		return ErrTaskRunning
+	}
+	c.state = Waiting
+	c.progress = MinProgress
+	c.stdout.Reset()
+	c.stderr.Reset()
+	c.exitCode = 0
+	c.err = nil
+	return nil
+}
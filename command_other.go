@@ -0,0 +1,20 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package gotask
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup Does nothing, process groups are not supported on this platform
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalGroup Sends signal to command only, as process groups are not supported on this platform
+func signalGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return cmd.Process.Kill()
+	}
+	return cmd.Process.Signal(sig)
+}
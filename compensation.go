@@ -0,0 +1,78 @@
+package gotask
+
+import (
+	"fmt"
+)
+
+// CompensationResult Outcome of the compensation of one task
+type CompensationResult struct {
+	Task string // name of compensated task
+	Err  error  // error returned by compensation, nil if successful
+}
+
+// CompensationError Error returned by Wait if compensations were run after a failed, stopped or timed out worker run
+// It unwraps to the original error of the run, so errors.Is still matches e.g. ErrWorkerCanceledByUser
+type CompensationError struct {
+	Err     error                // original error of the run
+	Results []CompensationResult // outcomes of all compensations in the order they were run
+}
+
+// Error Returns error message of original error and amount of failed compensations
+func (e *CompensationError) Error() string {
+	failed := 0
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%v (compensated %d tasks, %d compensations failed)", e.Err, len(e.Results), failed)
+}
+
+// Unwrap Returns original error of the run
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// Failed Checks if any compensation failed
+func (e *CompensationError) Failed() bool {
+	for _, result := range e.Results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// GetCompensations Returns outcomes of compensations run after the last failed, stopped or timed out run
+func (w *Worker) GetCompensations() []CompensationResult {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	results := make([]CompensationResult, len(w.compensations))
+	copy(results, w.compensations)
+	return results
+}
+
+// compensate Runs compensations of all finished tasks in reverse order and records their outcomes
+// A failing compensation does not prevent the remaining compensations from running.
+func (w *Worker) compensate() {
+	w.mu.Lock()
+	finished := w.finishedTasks
+	w.mu.Unlock()
+
+	var results []CompensationResult
+	for idx := len(finished) - 1; idx >= 0; idx-- {
+		compensable, ok := finished[idx].(Compensable)
+		if !ok || !compensable.HasCompensation() {
+			continue
+		}
+		results = append(results, CompensationResult{Task: finished[idx].GetName(), Err: compensable.Compensate()})
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.compensations = results
+	w.err = &CompensationError{Err: w.err, Results: results}
+}
@@ -0,0 +1,228 @@
+package gotask
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrCronInvalid error = errors.New("invalid cron expression")
+)
+
+// Schedule Defines when a scheduled worker run is due
+type Schedule interface {
+	Next(t time.Time) time.Time // returns next activation time strictly after t, zero time if there is none
+}
+
+// intervalSchedule Schedule with fixed interval between activations
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every Creates schedule which is activated every interval, intervals below one second are rounded up to one second
+func Every(interval time.Duration) Schedule {
+	if interval < time.Second {
+		interval = time.Second
+	}
+	return intervalSchedule{interval: interval}
+}
+
+// Next Returns time one interval after t
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// cronSchedule Schedule defined by a cron expression, every field is stored as bit set of allowed values
+type cronSchedule struct {
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool // day of month was defined as '*', then only day of week is relevant
+	dowStar bool // day of week was defined as '*', then only day of month is relevant
+}
+
+// cronField Allowed value range and names of one cron expression field
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minute", min: 0, max: 59}
+	cronHour   = cronField{name: "hour", min: 0, max: 23}
+	cronDom    = cronField{name: "day of month", min: 1, max: 31}
+	cronMonth  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6, "JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12}}
+	cronDow = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron Parses standard five field cron expression (minute, hour, day of month, month, day of week)
+// Supported are '*', values, ranges 'a-b', lists 'a,b', steps '*/n' or 'a-b/n', month and weekday names,
+// the descriptors @yearly, @monthly, @weekly, @daily, @hourly and '@every <duration>'.
+// Activation times are evaluated in the location of the time passed to Next.
+func ParseCron(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(expr[len("@every "):]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCronInvalid, err)
+		}
+		return Every(interval), nil
+	}
+	if descriptor, ok := cronDescriptors[expr]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d in '%s'", ErrCronInvalid, len(fields), expr)
+	}
+
+	var err error
+	schedule := cronSchedule{domStar: fields[2] == "*", dowStar: fields[4] == "*"}
+	if schedule.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = cronHour.parse(fields[1]); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = cronDom.parse(fields[2]); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = cronDow.parse(fields[4]); err != nil {
+		return nil, err
+	}
+	if schedule.dow&(1<<7) != 0 { // 7 is an alias for sunday
+		schedule.dow |= 1
+	}
+	return schedule, nil
+}
+
+// parse Parses comma separated list of field items into bit set
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		itemBits, err := f.parseItem(item)
+		if err != nil {
+			return 0, err
+		}
+		bits |= itemBits
+	}
+	return bits, nil
+}
+
+// parseItem Parses single field item, which can be '*', a value or a range with optional step
+func (f cronField) parseItem(item string) (uint64, error) {
+	rangePart, step := item, 1
+	if idx := strings.Index(item, "/"); idx >= 0 {
+		rangePart = item[:idx]
+		var err error
+		step, err = strconv.Atoi(item[idx+1:])
+		if err != nil || step <= 0 {
+			return 0, fmt.Errorf("%w: invalid step in %s field '%s'", ErrCronInvalid, f.name, item)
+		}
+	}
+
+	start, end := f.min, f.max
+	switch {
+	case rangePart == "*":
+	case strings.Contains(rangePart, "-"):
+		idx := strings.Index(rangePart, "-")
+		var err error
+		if start, err = f.parseValue(rangePart[:idx]); err != nil {
+			return 0, err
+		}
+		if end, err = f.parseValue(rangePart[idx+1:]); err != nil {
+			return 0, err
+		}
+	default:
+		value, err := f.parseValue(rangePart)
+		if err != nil {
+			return 0, err
+		}
+		start = value
+		if step == 1 {
+			end = value
+		}
+	}
+	if start > end {
+		return 0, fmt.Errorf("%w: invalid range in %s field '%s'", ErrCronInvalid, f.name, item)
+	}
+
+	var bits uint64
+	for value := start; value <= end; value += step {
+		bits |= 1 << uint(value)
+	}
+	return bits, nil
+}
+
+// parseValue Parses single numeric or named field value and validates its range
+func (f cronField) parseValue(value string) (int, error) {
+	if named, ok := f.names[strings.ToUpper(value)]; ok {
+		return named, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < f.min || parsed > f.max {
+		return 0, fmt.Errorf("%w: invalid %s value '%s'", ErrCronInvalid, f.name, value)
+	}
+	return parsed, nil
+}
+
+// Next Returns first minute after t matching the cron expression, searching at most five years ahead
+func (s cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+	for t.Year() <= yearLimit {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches Checks day of month and day of week, if both are restricted matching one of them is sufficient
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,113 @@
+package gotask
+
+import (
+	"sort"
+	"time"
+)
+
+// DryRunEvent Lifecycle event of worker, stage or task a run would emit
+type DryRunEvent struct {
+	At     time.Duration `json:"at"`               // projected time since start of run
+	Kind   string        `json:"kind"`             // "worker", "stage" or "task"
+	Name   string        `json:"name"`             // name of worker, stage or task
+	Stage  string        `json:"stage"`            // name of stage of task, empty for worker events
+	State  State         `json:"state"`            // state the worker, stage or task changes to
+	Reason string        `json:"reason,omitempty"` // why the task would be skipped, empty for all other events
+}
+
+// DryRunResult Projection of a run of a worker, computed without invoking any task
+type DryRunResult struct {
+	Events            []DryRunEvent `json:"events"` // lifecycle events in the order a run would emit them
+	TotalWeight       Weight        `json:"totalWeight"`
+	ProjectedDuration time.Duration `json:"projectedDuration"`
+	Timeout           time.Duration `json:"timeout"` // timeout of the projected run, zero if no timeout set
+	Fits              bool          `json:"fits"`    // true if neither the timeout nor a stage timeout would be reached
+}
+
+// DryRun Projects a run with timeout without invoking any task and without changing the worker
+// Stages and their tasks are walked in the order a run would start them, respecting the selection and the concurrency
+// of every stage. The duration of a task is projected by the same estimate as used by SetSkipOptional, so optional
+// tasks are projected as skipped if they would not fit. Rate limiters and shared resources are not taken into account.
+func (w *Worker) DryRun(timeout time.Duration) (DryRunResult, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return DryRunResult{}, ErrWorkerRunning
+	}
+
+	result := DryRunResult{Timeout: timeout, Fits: true}
+	if len(w.taskQueue) == 0 {
+		return result, nil
+	}
+	events := []DryRunEvent{{At: 0, Kind: "worker", Name: w.name, State: Running}}
+	at := time.Duration(0)
+	for _, stage := range w.stages {
+		events = append(events, DryRunEvent{At: at, Kind: "stage", Name: stage.name, State: Running})
+
+		// every task is started in the slot which becomes free first, as the run loop starts the next task once a
+		// running task completed
+		slots := make([]time.Duration, stage.concurrency)
+		for idx := range slots {
+			slots[idx] = at
+		}
+		end := at
+		deadline := time.Duration(0)
+		if timeout > 0 {
+			deadline = timeout
+		}
+		if stage.timeout > 0 && (deadline == 0 || at+stage.timeout < deadline) {
+			deadline = at + stage.timeout
+		}
+		for _, task := range stage.tasks {
+			if w.selection != nil && !w.selection.Matches(task) { // selected like at the start of a run, without storing it
+				events = append(events, DryRunEvent{At: at, Kind: "task", Name: task.GetName(), Stage: stage.name, State: Skipped,
+					Reason: skipNotSelected})
+				continue
+			}
+			slot := 0
+			for idx := range slots {
+				if slots[idx] < slots[slot] {
+					slot = idx
+				}
+			}
+			start := slots[slot]
+			duration := w.estimate(task)
+			if w.skipOptional && deadline > 0 && isOptional(task) && start+duration > deadline {
+				events = append(events, DryRunEvent{At: start, Kind: "task", Name: task.GetName(), Stage: stage.name, State: Skipped,
+					Reason: skipUnfit})
+				continue
+			}
+			slots[slot] += duration
+			if slots[slot] > end {
+				end = slots[slot]
+			}
+			result.TotalWeight += task.GetWeight()
+			events = append(events,
+				DryRunEvent{At: start, Kind: "task", Name: task.GetName(), Stage: stage.name, State: Running},
+				DryRunEvent{At: slots[slot], Kind: "task", Name: task.GetName(), Stage: stage.name, State: Finished})
+		}
+
+		if stage.timeout > 0 && end-at > stage.timeout {
+			result.Fits = false
+		}
+		at = end
+		events = append(events, DryRunEvent{At: at, Kind: "stage", Name: stage.name, State: Finished})
+	}
+	events = append(events, DryRunEvent{At: at, Kind: "worker", Name: w.name, State: Finished})
+
+	// events are recorded per task, the stable sort keeps their order for equal times
+	sort.SliceStable(events, func(i int, j int) bool {
+		return events[i].At < events[j].At
+	})
+	result.Events = events
+	result.ProjectedDuration = at
+	if timeout > 0 && at > timeout {
+		result.Fits = false
+	}
+	return result, nil
+}
+
+// weightDuration Returns work time resembled by weight, one second per weight unit
+func weightDuration(weight Weight) time.Duration {
+	return time.Duration(float64(weight) * float64(time.Second))
+}
@@ -0,0 +1,288 @@
+package gotask
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FanOut Composite task which runs a target for every item of a collection as child tasks and reduces their results
+// The progress of a FanOut grows with every completed child, weighted by the child weights.
+type FanOut struct {
+	mu          sync.Mutex
+	name        string
+	desc        string
+	items       []interface{}
+	target      func(item interface{}) (interface{}, error) // target run for every item, returns result of item
+	itemName    func(idx int, item interface{}) string      // returns name of child task of item
+	itemWeight  func(item interface{}) Weight               // returns weight of child task of item
+	concurrency int                                         // maximum amount of children running at the same time
+	reduce      func(acc interface{}, result interface{}) interface{}
+	initial     interface{} // initial accumulator passed to reduce
+	children    []*Task
+	results     []interface{} // results of children in item order
+	result      interface{}   // reduced result of last run
+	state       State
+	err         error
+	handle      *Handle
+}
+
+// NewFanOut Factory method for creating a new fan out task running target for every item
+// By default children are named after the fan out and the item index, have a weight of one and run sequentially.
+func NewFanOut(name string, desc string, items []interface{}, target func(item interface{}) (interface{}, error)) *FanOut {
+	fanOut := FanOut{
+		name:        name,
+		desc:        desc,
+		items:       items,
+		target:      target,
+		concurrency: 1,
+		state:       Waiting,
+	}
+	fanOut.itemName = func(idx int, item interface{}) string {
+		return fmt.Sprintf("%s [%d]", fanOut.name, idx)
+	}
+	fanOut.itemWeight = func(item interface{}) Weight {
+		return 1
+	}
+	fanOut.children = fanOut.createChildren()
+	return &fanOut
+}
+
+// SetItemName Sets function returning name of child task for item, returns fan out for chaining
+func (f *FanOut) SetItemName(itemName func(idx int, item interface{}) string) *FanOut {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.itemName = itemName
+	f.children = f.createChildren()
+	return f
+}
+
+// SetItemWeight Sets function returning weight of child task for item, returns fan out for chaining
+func (f *FanOut) SetItemWeight(itemWeight func(item interface{}) Weight) *FanOut {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.itemWeight = itemWeight
+	f.children = f.createChildren()
+	return f
+}
+
+// SetConcurrency Sets maximum amount of children running at the same time, values below one are set to one
+// Returns fan out for chaining
+func (f *FanOut) SetConcurrency(concurrency int) *FanOut {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	f.concurrency = concurrency
+	return f
+}
+
+// SetReduce Sets function combining the results of all children in item order, starting with initial
+// Returns fan out for chaining
+func (f *FanOut) SetReduce(initial interface{}, reduce func(acc interface{}, result interface{}) interface{}) *FanOut {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.initial = initial
+	f.reduce = reduce
+	return f
+}
+
+// Run Runs target for all items and reduces their results, this is called by worker
+// No more children are started once a child failed or the worker was stopped or timed out.
+func (f *FanOut) Run() {
+	f.mu.Lock()
+	f.state = Running
+	f.err = nil
+	f.result = nil
+	f.children = f.createChildren()
+	f.results = make([]interface{}, len(f.items))
+	children, concurrency, handle := f.children, f.concurrency, f.handle
+	f.mu.Unlock()
+
+	var done <-chan struct{}
+	if handle != nil {
+		done = handle.Done()
+	}
+
+	var wg sync.WaitGroup
+	var errMu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, concurrency)
+	canceled := false
+	failed := func() bool {
+		errMu.Lock()
+		defer errMu.Unlock()
+		return firstErr != nil
+	}
+
+	for _, child := range children {
+		select {
+		case slots <- struct{}{}:
+		case <-done:
+			canceled = true
+		}
+		if canceled || failed() {
+			break
+		}
+
+		wg.Add(1)
+		go func(child *Task) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			child.Run()
+			if err := child.GetError(); err != nil {
+				errMu.Lock()
+				if firstErr == nil {
+					firstErr = &TaskError{Task: child.GetName(), Err: err}
+				}
+				errMu.Unlock()
+			}
+		}(child)
+	}
+	wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case firstErr != nil:
+		f.state = Failed
+		f.err = firstErr
+	case canceled:
+		f.state = Canceled
+		f.err = handle.Err()
+	default:
+		f.state = Finished
+		f.result = f.initial
+		if f.reduce != nil {
+			for _, result := range f.results {
+				f.result = f.reduce(f.result, result)
+			}
+		}
+	}
+}
+
+// createChildren Creates child task for every item, caller must hold the lock
+func (f *FanOut) createChildren() []*Task {
+	children := make([]*Task, len(f.items))
+	for idx, item := range f.items {
+		idx := idx
+		children[idx] = NewTask(f.itemName(idx, item), f.itemWeight(item), f.desc, func(arg interface{}) error {
+			result, err := f.target(arg)
+			f.mu.Lock()
+			f.results[idx] = result
+			f.mu.Unlock()
+			return err
+		}, item)
+	}
+	return children
+}
+
+// bind Stores handle of worker which is about to run the fan out
+func (f *FanOut) bind(h *Handle) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handle = h
+}
+
+// GetName Returns fan out name
+func (f *FanOut) GetName() string {
+	return f.name
+}
+
+// GetState Returns fan out state
+func (f *FanOut) GetState() State {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+// GetProgress Returns share of completed child weight in percent
+func (f *FanOut) GetProgress() Progress {
+	f.mu.Lock()
+	children := f.children
+	f.mu.Unlock()
+
+	workTotal := 0.0
+	workDone := 0.0
+	for _, child := range children {
+		workTotal += float64(child.GetWeight())
+		workDone += float64(child.GetProgress()) / float64(MaxProgress) * float64(child.GetWeight())
+	}
+	if workTotal == 0 {
+		if f.GetState() == Finished {
+			return MaxProgress
+		}
+		return MinProgress
+	}
+	return Progress(workDone/workTotal) * MaxProgress
+}
+
+// GetWeight Returns combined weight of all children
+func (f *FanOut) GetWeight() Weight {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	weight := Weight(0)
+	for _, child := range f.children {
+		weight += child.GetWeight()
+	}
+	return weight
+}
+
+// GetDesc Returns fan out description
+func (f *FanOut) GetDesc() string {
+	return f.desc
+}
+
+// GetWorkLoad Returns fan out workload (progress times weight)
+func (f *FanOut) GetWorkLoad() int {
+	return int(float64(f.GetProgress()) * float64(f.GetWeight()) / float64(MaxProgress))
+}
+
+// GetError Returns TaskError of first failed child or the cancel reason of the worker, nil if successful
+func (f *FanOut) GetError() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// GetChildren Returns child tasks of the present or last run in item order
+func (f *FanOut) GetChildren() []Runnable {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	children := make([]Runnable, len(f.children))
+	for idx, child := range f.children {
+		children[idx] = child
+	}
+	return children
+}
+
+// GetResults Returns results of all children in item order, nil for children which did not run
+func (f *FanOut) GetResults() []interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results := make([]interface{}, len(f.results))
+	copy(results, f.results)
+	return results
+}
+
+// GetResult Returns reduced result of last successful run, nil if no reduce function set
+func (f *FanOut) GetResult() interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.result
+}
+
+// Reset Resets fan out and all children to start state
+func (f *FanOut) Reset() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.state == Running {
+		return ErrTaskRunning
+	}
+	f.state = Waiting
+	f.err = nil
+	f.result = nil
+	f.results = nil
+	f.children = f.createChildren()
+	return nil
+}
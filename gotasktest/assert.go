@@ -0,0 +1,110 @@
+package gotasktest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// WaitTimeout Time the wait helpers wait for a condition before failing the test
+var WaitTimeout = time.Second
+
+// Stater Implemented by workers, stages and tasks
+type Stater interface {
+	GetState() gotask.State
+}
+
+// WaitFor Waits until cond is true and fails the test if it is not true within WaitTimeout
+func WaitFor(t testing.TB, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(WaitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %v", WaitTimeout)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// WaitForState Waits until subject reached state and fails the test if it is not reached within WaitTimeout
+func WaitForState(t testing.TB, subject Stater, state gotask.State) {
+	t.Helper()
+	deadline := time.Now().Add(WaitTimeout)
+	for subject.GetState() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("state not %v within %v: %v", state, WaitTimeout, subject.GetState())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// AssertState Fails the test if subject is not in state
+func AssertState(t testing.TB, subject Stater, state gotask.State) {
+	t.Helper()
+	if actual := subject.GetState(); actual != state {
+		t.Errorf("state not %v: %v", state, actual)
+	}
+}
+
+// AssertStates Fails the test if states is not equal to expected
+func AssertStates(t testing.TB, states []gotask.State, expected ...gotask.State) {
+	t.Helper()
+	if len(states) != len(expected) {
+		t.Errorf("states not %v: %v", expected, states)
+		return
+	}
+	for idx := range states {
+		if states[idx] != expected[idx] {
+			t.Errorf("states not %v: %v", expected, states)
+			return
+		}
+	}
+}
+
+// Recorder Records the state sequences of all tasks of a worker, set as middleware with Worker.Use
+// Every run of a task records Running and the state the task ended in.
+type Recorder struct {
+	mu     sync.Mutex
+	states map[string][]gotask.State
+}
+
+// NewRecorder Factory method for creating a new recorder
+func NewRecorder() *Recorder {
+	recorder := Recorder{states: make(map[string][]gotask.State)}
+	return &recorder
+}
+
+// Middleware Returns middleware recording the states of every task it wraps
+func (r *Recorder) Middleware() gotask.Middleware {
+	return func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			task := h.GetTask()
+			r.record(task.GetName(), gotask.Running)
+			err := next(h)
+			r.record(task.GetName(), task.GetState())
+			return err
+		}
+	}
+}
+
+// record Appends state to sequence of task
+func (r *Recorder) record(task string, state gotask.State) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[task] = append(r.states[task], state)
+}
+
+// GetStates Returns copy of recorded state sequence of task
+func (r *Recorder) GetStates(task string) []gotask.State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]gotask.State(nil), r.states[task]...)
+}
+
+// AssertStates Fails the test if the recorded state sequence of task is not equal to expected
+func (r *Recorder) AssertStates(t testing.TB, task string, expected ...gotask.State) {
+	t.Helper()
+	AssertStates(t, r.GetStates(task), expected...)
+}
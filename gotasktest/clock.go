@@ -0,0 +1,71 @@
+// Package gotasktest provides utilities for testing code built on gotask deterministically and without waiting real
+// time: a manually advanced clock, fake tasks controlled by the test and assertion helpers for states.
+package gotasktest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock Manually advanced clock implementing gotask.Clock, e.g. set with Worker.SetClock or Scheduler.SetClock
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter Channel of an After call which receives once the clock reached until
+type fakeWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// NewFakeClock Factory method for creating a new fake clock showing now until it is advanced
+func NewFakeClock(now time.Time) *FakeClock {
+	clock := FakeClock{now: now}
+	return &clock
+}
+
+// Now Returns current time of clock
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After Returns channel which receives the current time once the clock was advanced by duration
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{until: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance Moves clock forward and fires all waiters which are due
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	remaining := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if !waiter.until.After(c.now) {
+			waiter.ch <- c.now
+		} else {
+			remaining = append(remaining, waiter)
+		}
+	}
+	c.waiters = remaining
+}
+
+// GetWaiters Returns amount of After calls waiting for the clock to be advanced
+// Tests can wait for the code under test to start waiting before advancing the clock, see WaitFor.
+func (c *FakeClock) GetWaiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
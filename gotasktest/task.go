@@ -0,0 +1,70 @@
+package gotasktest
+
+import (
+	"sync"
+
+	"github.com/morgadow/gotask"
+)
+
+// FakeTask Task whose runs block until the test releases or fails them
+// A run also ends once the worker is stopped or times out, with the cancel reason as error. Outcomes can be given
+// before the task is started, every run consumes one outcome.
+type FakeTask struct {
+	*gotask.Task
+	mu       sync.Mutex
+	outcomes chan error
+	running  chan struct{} // receives once a run started, runs not received yet are merged
+	runs     int
+}
+
+// NewFakeTask Factory method for creating a new fake task
+func NewFakeTask(name string, weight gotask.Weight) *FakeTask {
+	task := FakeTask{
+		outcomes: make(chan error, 64),
+		running:  make(chan struct{}, 1),
+	}
+	task.Task = gotask.NewHandleTask(name, weight, "fake task", task.run, nil)
+	return &task
+}
+
+// run Target of task, blocks until an outcome is given or the worker stopped
+func (f *FakeTask) run(h *gotask.Handle, arg interface{}) error {
+	f.mu.Lock()
+	f.runs++
+	f.mu.Unlock()
+	// the signal never blocks the run, so tests not receiving it do not stall the worker
+	select {
+	case f.running <- struct{}{}:
+	default:
+	}
+
+	select {
+	case err := <-f.outcomes:
+		return err
+	case <-h.Done():
+		return h.Err()
+	}
+}
+
+// Release Lets the present or next run finish successfully
+func (f *FakeTask) Release() {
+	f.outcomes <- nil
+}
+
+// Fail Lets the present or next run fail with err
+func (f *FakeTask) Fail(err error) {
+	f.outcomes <- err
+}
+
+// Started Returns channel receiving once a run started
+// Runs started before the last signal was received are signaled only once, use GetRuns to count the runs.
+func (f *FakeTask) Started() <-chan struct{} {
+	return f.running
+}
+
+// GetRuns Returns amount of started runs
+func (f *FakeTask) GetRuns() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.runs
+}
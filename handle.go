@@ -0,0 +1,94 @@
+package gotask
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// Handle Gives a running task access to the worker executing it, e.g. to enqueue further tasks it discovered during its run
+type Handle struct {
+	worker *Worker
+	task   Runnable
+	stage  *Stage // stage the task belongs to, subtasks are added to the same stage
+	signal *cancelSignal
+	output *Output
+}
+
+// bindable Implemented by tasks which want to receive a handle from the worker right before they are run
+type bindable interface {
+	bind(h *Handle)
+}
+
+// GetWorker Returns worker the task is running in, nil if task is run without a worker
+func (h *Handle) GetWorker() *Worker {
+	return h.worker
+}
+
+// GetTask Returns task this handle belongs to
+func (h *Handle) GetTask() Runnable {
+	return h.task
+}
+
+// GetStage Returns stage the task belongs to, nil if task is run without a worker
+func (h *Handle) GetStage() *Stage {
+	return h.stage
+}
+
+// Done Returns channel which is closed once the worker was stopped or timed out, so long running tasks can give up early
+// Returns nil if task is run without a worker, which never closes
+func (h *Handle) Done() <-chan struct{} {
+	if h.signal == nil {
+		return nil
+	}
+	return h.signal.done
+}
+
+// Err Returns ErrWorkerCanceledByUser or the timeout error once Done is closed, nil before
+func (h *Handle) Err() error {
+	if h.signal == nil {
+		return nil
+	}
+	select {
+	case <-h.signal.done:
+		return h.signal.err
+	default:
+		return nil
+	}
+}
+
+// after Returns channel receiving once duration elapsed on the clock of the worker, the system clock if run without one
+func (h *Handle) after(d time.Duration) <-chan time.Time {
+	if h.worker == nil {
+		return SystemClock.After(d)
+	}
+	h.worker.mu.Lock()
+	clock := h.worker.clock
+	h.worker.mu.Unlock()
+	return clock.After(d)
+}
+
+// AddTask Appends new task to the stage of this task in the worker running it
+func (h *Handle) AddTask(task Runnable) error {
+	return h.AddTasks([]Runnable{task})
+}
+
+// AddTasks Appends multiple new tasks to the stage of this task in the worker running it
+func (h *Handle) AddTasks(tasks []Runnable) error {
+	if h.worker == nil {
+		return ErrWorkerNotBound
+	}
+	if h.stage == nil {
+		return h.worker.AddTasks(tasks)
+	}
+	return h.stage.AddTasks(tasks)
+}
+
+// GetOutput Returns output sink of the task run, whose content can be retrieved over GetOutput of the worker
+// Returns a writer discarding all output if task is run without a worker
+func (h *Handle) GetOutput() io.Writer {
+	if h.output == nil {
+		return ioutil.Discard
+	}
+	return h.output
+}
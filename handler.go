@@ -1,5 +1,15 @@
 package gotask
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrStateUnknown error = errors.New("unknown state")
+)
+
 type State uint8      // State of Task and or worker
 type Progress float64 // Worker and Task Progress, Note: Task can be either done or not done, it has no float progress
 type Weight float64   // Weighting of task; a weight of 1 resembles ca. 1 second work time
@@ -13,17 +23,74 @@ const (
 	Canceled       State = iota // Worker was stopped before finished due to timeout or due to user cancelled it
 	Finished       State = iota // Task or Worker finished. To rerun again call the reset method
 	TimeoutReached State = iota // Worker did not finish in time, equal to Canceled
+	Failed         State = iota // Task returned an error or Worker stopped due to a failed task
+	Skipped        State = iota // Task was not run as it was not selected, could not finish in time or middleware did not run it
 )
 
-var stateToString = map[State]string{Waiting: "WAITING", Running: "RUNNING", Canceled: "Canceled", Finished: "FINISHED", TimeoutReached: "TIMEOUT"}
-var stringToState = map[string]State{"WAITING": Waiting, "RUNNING": Running, "CANCELED": Canceled, "FINISHED": Finished, "TIMEOUT": TimeoutReached}
+var stateToString = map[State]string{Waiting: "WAITING", Running: "RUNNING", Canceled: "CANCELED", Finished: "FINISHED", TimeoutReached: "TIMEOUT", Failed: "FAILED", Skipped: "SKIPPED"}
+var stringToState = map[string]State{"WAITING": Waiting, "RUNNING": Running, "CANCELED": Canceled, "FINISHED": Finished, "TIMEOUT": TimeoutReached, "FAILED": Failed, "SKIPPED": Skipped}
 
 // StateToString Converts task state to string equivalent
 func StateToString(state State) string {
 	return stateToString[state]
 }
 
-// StateToString Converts task state to string equivalent
+// StringToState Converts string to task state equivalent
+// Note: Unknown names are converted to Waiting, use ParseState to detect them
 func StringToState(name string) State {
 	return stringToState[name]
 }
+
+// ParseState Converts string to task state equivalent, returns ErrStateUnknown for unknown names
+func ParseState(name string) (State, error) {
+	state, ok := stringToState[name]
+	if !ok {
+		return Waiting, fmt.Errorf("%w: '%s'", ErrStateUnknown, name)
+	}
+	return state, nil
+}
+
+// String Returns name of state, unknown states are named by their number
+func (s State) String() string {
+	if name, ok := stateToString[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("State(%d)", uint8(s))
+}
+
+// MarshalText Returns name of state, which is also used for JSON, returns ErrStateUnknown for unknown states
+func (s State) MarshalText() ([]byte, error) {
+	name, ok := stateToString[s]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrStateUnknown, uint8(s))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText Sets state from its name, which is also used for JSON, returns ErrStateUnknown for unknown names
+func (s *State) UnmarshalText(text []byte) error {
+	state, err := ParseState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = state
+	return nil
+}
+
+// UnmarshalJSON Sets state from its name, numbers written by earlier versions, e.g. in queue journals, are accepted
+// Returns ErrStateUnknown for unknown names or numbers
+func (s *State) UnmarshalJSON(data []byte) error {
+	var number uint8
+	if err := json.Unmarshal(data, &number); err == nil {
+		if _, ok := stateToString[State(number)]; !ok {
+			return fmt.Errorf("%w: %d", ErrStateUnknown, number)
+		}
+		*s = State(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	return s.UnmarshalText([]byte(name))
+}
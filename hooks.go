@@ -0,0 +1,233 @@
+package gotask
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrWorkerHookFailed      error = errors.New("worker hook failed")
+	ErrFinallyTimeoutReached error = errors.New("finally task reached timeout limit")
+)
+
+// DefaultFinallyTimeout Default time every finally task has to complete
+const DefaultFinallyTimeout = 30 * time.Second
+
+// HookError Error of a hook or finally task
+type HookError struct {
+	Hook string // "BeforeAll", "AfterAll", "BeforeEach", "AfterEach" or "Finally"
+	Task string // name of task the hook was run for or of the finally task, empty for BeforeAll and AfterAll
+	Err  error  // error returned by hook or finally task
+}
+
+// Error Returns error message containing hook, task and error
+func (e *HookError) Error() string {
+	if e.Task == "" {
+		return fmt.Sprintf("%v '%s': %v", ErrWorkerHookFailed, e.Hook, e.Err)
+	}
+	return fmt.Sprintf("%v '%s' of task '%s': %v", ErrWorkerHookFailed, e.Hook, e.Task, e.Err)
+}
+
+// Unwrap Returns error returned by hook or finally task
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// Is Reports HookError to match ErrWorkerHookFailed
+func (e *HookError) Is(target error) bool {
+	return target == ErrWorkerHookFailed
+}
+
+// SetBeforeAll Sets hook run before the first task, if it fails no task is run and the run fails with a HookError
+func (w *Worker) SetBeforeAll(hook func() error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.beforeAll = hook
+	return nil
+}
+
+// SetAfterAll Sets hook run after all tasks, also if the run failed, was stopped or timed out
+// Its error does not change the outcome of the run and is returned by GetHookErrors.
+func (w *Worker) SetAfterAll(hook func() error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.afterAll = hook
+	return nil
+}
+
+// SetBeforeEach Sets hook run before every task, if it fails the task is not run and fails with a HookError
+func (w *Worker) SetBeforeEach(hook func(task Runnable) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.beforeEach = hook
+	return nil
+}
+
+// SetAfterEach Sets hook run after every task, also if the task failed. The outcome is available from the task.
+// Its error does not change the outcome of the task and is returned by GetHookErrors.
+func (w *Worker) SetAfterEach(hook func(task Runnable) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.afterEach = hook
+	return nil
+}
+
+// AddFinally Adds task which is always run at the end of a run, also if the run failed, was stopped or timed out
+// Finally tasks are run one after another once the worker reached its final state and compensations were run, Wait
+// returns after they completed. Every finally task has its own timeout, see SetFinallyTimeout. Their errors do not
+// change the outcome of the run and are returned by GetHookErrors.
+func (w *Worker) AddFinally(task Runnable) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.finally = append(w.finally, task)
+	return nil
+}
+
+// SetFinallyTimeout Sets time every finally task has to complete, default is DefaultFinallyTimeout
+// Once reached, the task is signaled to stop via its handle and the worker does not wait for it any longer.
+func (w *Worker) SetFinallyTimeout(timeout time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.finallyTimeout = timeout
+	return nil
+}
+
+// GetFinally Returns copy of all finally tasks as slice
+func (w *Worker) GetFinally() []Runnable {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tasks := make([]Runnable, len(w.finally))
+	copy(tasks, w.finally)
+	return tasks
+}
+
+// GetHookErrors Returns errors of AfterEach and AfterAll hooks and finally tasks of the present or last run
+func (w *Worker) GetHookErrors() []*HookError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	errs := make([]*HookError, len(w.hookErrors))
+	copy(errs, w.hookErrors)
+	return errs
+}
+
+// runBeforeAll Runs BeforeAll hook, returns HookError if it failed
+func (w *Worker) runBeforeAll() error {
+	w.mu.Lock()
+	hook := w.beforeAll
+	w.mu.Unlock()
+	if hook == nil {
+		return nil
+	}
+	if err := hook(); err != nil {
+		return &HookError{Hook: "BeforeAll", Err: err}
+	}
+	return nil
+}
+
+// runAfterAll Runs AfterAll hook and records its error
+func (w *Worker) runAfterAll() {
+	w.mu.Lock()
+	hook := w.afterAll
+	w.mu.Unlock()
+	if hook == nil {
+		return
+	}
+	if err := hook(); err != nil {
+		w.recordHookError(&HookError{Hook: "AfterAll", Err: err})
+	}
+}
+
+// runBeforeEach Runs BeforeEach hook for task, returns TaskError wrapping a HookError if it failed
+func (w *Worker) runBeforeEach(task Runnable) error {
+	w.mu.Lock()
+	hook := w.beforeEach
+	w.mu.Unlock()
+	if hook == nil {
+		return nil
+	}
+	if err := hook(task); err != nil {
+		return &TaskError{Task: task.GetName(), Err: &HookError{Hook: "BeforeEach", Task: task.GetName(), Err: err}}
+	}
+	return nil
+}
+
+// runAfterEach Runs AfterEach hook for task and records its error
+func (w *Worker) runAfterEach(task Runnable) {
+	w.mu.Lock()
+	hook := w.afterEach
+	w.mu.Unlock()
+	if hook == nil {
+		return
+	}
+	if err := hook(task); err != nil {
+		w.recordHookError(&HookError{Hook: "AfterEach", Task: task.GetName(), Err: err})
+	}
+}
+
+// runFinally Runs all finally tasks one after another, each with the finally timeout
+func (w *Worker) runFinally() {
+	w.mu.Lock()
+	tasks := make([]Runnable, len(w.finally))
+	copy(tasks, w.finally)
+	timeout, clock := w.finallyTimeout, w.clock
+	w.mu.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultFinallyTimeout
+	}
+
+	for _, task := range tasks {
+		_ = task.Reset()
+		signal := &cancelSignal{done: make(chan struct{})}
+		output := w.newTaskOutput(task)
+		if b, ok := task.(bindable); ok {
+			b.bind(&Handle{worker: w, task: task, signal: signal, output: output})
+		}
+
+		// the task goroutine does not touch the worker, as it is abandoned once the timeout is reached
+		done := make(chan struct{})
+		w.recordStart(task)
+		go func(task Runnable) {
+			defer close(done)
+			task.Run()
+			output.close()
+		}(task)
+
+		select {
+		case <-done:
+			w.recordEnd(task)
+			if failable, ok := task.(Failable); ok && failable.GetError() != nil {
+				w.recordHookError(&HookError{Hook: "Finally", Task: task.GetName(), Err: failable.GetError()})
+			}
+		case <-clock.After(timeout):
+			signal.cancel(ErrFinallyTimeoutReached)
+			w.recordEnd(task)
+			w.recordHookError(&HookError{Hook: "Finally", Task: task.GetName(), Err: ErrFinallyTimeoutReached})
+		}
+	}
+}
+
+// recordHookError Records error of hook or finally task
+func (w *Worker) recordHookError(err *HookError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.hookErrors = append(w.hookErrors, err)
+}
@@ -0,0 +1,464 @@
+package gotask
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrHTTPStatus           error = errors.New("unexpected response status")
+	ErrHTTPChecksumMismatch error = errors.New("checksum mismatch")
+)
+
+// HTTPStatusError Error of a request answered with an unexpected status code
+type HTTPStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string // status line, e.g. "404 Not Found"
+}
+
+// Error Returns error message containing url and status
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("%v: %s returned '%s'", ErrHTTPStatus, e.URL, e.Status)
+}
+
+// Is Reports that every status error is an ErrHTTPStatus
+func (e *HTTPStatusError) Is(target error) bool {
+	return target == ErrHTTPStatus
+}
+
+// HTTPTask Task performing an HTTP request or downloading a file
+// The progress is derived from the Content-Length of the response. Downloads are written to a ".part" file next to the
+// destination which is renamed once the download completed and the checksum matched, so an interrupted download is
+// resumed with a Range request on the next run. The ETag or Last-Modified of the response is stored in a ".part.validator"
+// file and sent as If-Range, so a remote file changed meanwhile is downloaded again instead of being appended to the part.
+// Parts without validator are downloaded again as well. The request is canceled once the worker is stopped or times out.
+type HTTPTask struct {
+	mu       sync.Mutex
+	name     string
+	desc     string
+	weight   Weight
+	method   string
+	url      string
+	header   http.Header
+	body     []byte
+	client   *http.Client
+	expected []int            // accepted status codes, any 2xx status if empty
+	dest     string           // destination file of download, response body is kept in memory if empty
+	hashFunc func() hash.Hash // creates hash to verify checksum with, nil if not verified
+	checksum string           // expected checksum in hex
+	state    State
+	status   int
+	received int64 // bytes of body received including a resumed part
+	total    int64 // expected bytes of body including a resumed part, -1 if unknown
+	response bytes.Buffer
+	err      error
+	handle   *Handle
+}
+
+// NewHTTPTask Factory method for creating a new task performing an HTTP request, whose response body is kept in memory
+func NewHTTPTask(name string, weight Weight, desc string, method string, url string) *HTTPTask {
+	task := HTTPTask{
+		name:   name,
+		desc:   desc,
+		weight: weight,
+		method: method,
+		url:    url,
+		header: make(http.Header),
+		client: http.DefaultClient,
+		state:  Waiting,
+		total:  -1,
+	}
+	return &task
+}
+
+// NewDownloadTask Factory method for creating a new task downloading url to file dest
+func NewDownloadTask(name string, weight Weight, desc string, url string, dest string) *HTTPTask {
+	task := NewHTTPTask(name, weight, desc, http.MethodGet, url)
+	task.dest = dest
+	return task
+}
+
+// SetHeader Sets request header, returns task for chaining
+func (h *HTTPTask) SetHeader(key string, value string) *HTTPTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header.Set(key, value)
+	return h
+}
+
+// SetBody Sets request body, returns task for chaining
+func (h *HTTPTask) SetBody(body []byte) *HTTPTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.body = body
+	return h
+}
+
+// SetClient Sets client used for the request, e.g. for timeouts or proxies, returns task for chaining
+func (h *HTTPTask) SetClient(client *http.Client) *HTTPTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.client = client
+	return h
+}
+
+// SetExpectedStatus Sets accepted status codes of the response, by default any 2xx status is accepted
+// Returns task for chaining
+func (h *HTTPTask) SetExpectedStatus(codes ...int) *HTTPTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expected = codes
+	return h
+}
+
+// SetChecksum Sets checksum in hex the response body must match, e.g. sha256.New and the published sha256 sum
+// Returns task for chaining
+func (h *HTTPTask) SetChecksum(hashFunc func() hash.Hash, checksum string) *HTTPTask {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hashFunc = hashFunc
+	h.checksum = checksum
+	return h
+}
+
+// Run Performs request and reads the response body, this is called by worker
+func (h *HTTPTask) Run() {
+	h.mu.Lock()
+	h.state = Running
+	h.status = 0
+	h.received = 0
+	h.total = -1
+	h.response.Reset()
+	h.err = nil
+	handle := h.handle
+	h.mu.Unlock()
+
+	var done <-chan struct{}
+	if handle != nil {
+		done = handle.Done()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := h.perform(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case handle != nil && handle.Err() != nil:
+		h.state = Canceled
+		h.err = handle.Err()
+	case err != nil:
+		h.state = Failed
+		h.err = err
+	default:
+		h.state = Finished
+	}
+}
+
+// perform Sends request and writes response body to memory or the part file of the destination
+func (h *HTTPTask) perform(ctx context.Context) error {
+	h.mu.Lock()
+	method, url, dest, client := h.method, h.url, h.dest, h.client
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(h.body))
+	if err != nil {
+		h.mu.Unlock()
+		return err
+	}
+	req.Header = h.header.Clone()
+	h.mu.Unlock()
+
+	part := dest + ".part"
+	offset := int64(0)
+	if dest != "" {
+		info, err := os.Stat(part)
+		validator, _ := ioutil.ReadFile(part + ".validator")
+		if err == nil && len(validator) > 0 {
+			offset = info.Size()
+			req.Header.Set("If-Range", string(validator))
+		}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	h.mu.Lock()
+	h.status = resp.StatusCode
+	h.mu.Unlock()
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		if completeLength(resp) == offset {
+			return h.finishDownload(part, dest) // the part file is already complete
+		}
+		// the part file does not match the length of the remote file, so the download starts over
+		resp.Body.Close()
+		removePart(part)
+		return h.perform(ctx)
+	case offset > 0 && resp.StatusCode != http.StatusPartialContent:
+		offset = 0 // the server ignored the range or the remote file changed, so the download starts over
+	}
+	if !h.accepted(resp.StatusCode) {
+		return &HTTPStatusError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	if dest != "" && offset == 0 {
+		if err := writeValidator(part+".validator", resp); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	h.received = offset
+	if resp.ContentLength >= 0 {
+		h.total = offset + resp.ContentLength
+	}
+	h.mu.Unlock()
+
+	if dest == "" {
+		_, err := io.Copy(&httpProgress{task: h, out: &h.response}, resp.Body)
+		if err != nil {
+			return err
+		}
+		return h.verify(bytes.NewReader(h.GetBody()))
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	file, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(&httpProgress{task: h, out: file}, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return h.finishDownload(part, dest)
+}
+
+// finishDownload Verifies checksum of completed part file and renames it to the destination
+// A part file not matching the checksum is removed, so the next run downloads it again.
+func (h *HTTPTask) finishDownload(part string, dest string) error {
+	file, err := os.Open(part)
+	if err != nil {
+		return err
+	}
+	err = h.verify(file)
+	file.Close()
+	if err != nil {
+		removePart(part)
+		return err
+	}
+
+	h.mu.Lock()
+	h.received = h.total
+	if h.total < 0 {
+		if info, err := os.Stat(part); err == nil {
+			h.received, h.total = info.Size(), info.Size()
+		}
+	}
+	h.mu.Unlock()
+	if err := os.Rename(part, dest); err != nil {
+		return err
+	}
+	_ = os.Remove(part + ".validator")
+	return nil
+}
+
+// writeValidator Stores strong ETag or Last-Modified of response in file, which identify the version of the remote
+// file when resuming. The file is removed if the response has neither, so the part is not resumed.
+func writeValidator(file string, resp *http.Response) error {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") { // If-Range only allows strong ETags
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return ioutil.WriteFile(file, []byte(validator), 0644)
+}
+
+// removePart Removes part file and its validator, so the next run downloads the file again
+func removePart(part string) {
+	_ = os.Remove(part)
+	_ = os.Remove(part + ".validator")
+}
+
+// completeLength Returns length of the remote file from the Content-Range of a 416 response, -1 if unknown
+func completeLength(resp *http.Response) int64 {
+	var length int64
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes */%d", &length); err != nil {
+		return -1
+	}
+	return length
+}
+
+// verify Compares checksum of content with expected checksum, does nothing if no checksum is set
+func (h *HTTPTask) verify(content io.Reader) error {
+	h.mu.Lock()
+	hashFunc, checksum := h.hashFunc, h.checksum
+	h.mu.Unlock()
+	if hashFunc == nil {
+		return nil
+	}
+	hasher := hashFunc()
+	if _, err := io.Copy(hasher, content); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); actual != checksum {
+		return fmt.Errorf("%w: expected %s, got %s", ErrHTTPChecksumMismatch, checksum, actual)
+	}
+	return nil
+}
+
+// accepted Checks if status code is accepted
+func (h *HTTPTask) accepted(code int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.expected) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, expected := range h.expected {
+		if code == expected {
+			return true
+		}
+	}
+	return false
+}
+
+// httpProgress Counts bytes of the response body written to out
+type httpProgress struct {
+	task *HTTPTask
+	out  io.Writer
+}
+
+// Write Writes body part to out and counts it
+func (p *httpProgress) Write(b []byte) (int, error) {
+	p.task.mu.Lock()
+	defer p.task.mu.Unlock()
+	n, err := p.out.Write(b)
+	p.task.received += int64(n)
+	return n, err
+}
+
+// bind Stores handle of worker which is about to run the task
+func (h *HTTPTask) bind(handle *Handle) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handle = handle
+}
+
+// GetName Returns task name
+func (h *HTTPTask) GetName() string {
+	return h.name
+}
+
+// GetState Returns task state
+func (h *HTTPTask) GetState() State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.state
+}
+
+// GetProgress Returns share of received bytes of the response body, stays zero while the Content-Length is unknown
+func (h *HTTPTask) GetProgress() Progress {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state == Finished {
+		return MaxProgress
+	}
+	if h.total <= 0 {
+		return MinProgress
+	}
+	return Progress(float64(h.received) / float64(h.total) * float64(MaxProgress))
+}
+
+// GetWeight Returns task weight
+func (h *HTTPTask) GetWeight() Weight {
+	return h.weight
+}
+
+// GetDesc Returns task description
+func (h *HTTPTask) GetDesc() string {
+	return h.desc
+}
+
+// GetWorkLoad Returns task workload (progress times weight)
+func (h *HTTPTask) GetWorkLoad() int {
+	return int(float64(h.GetProgress()) * float64(h.weight) / float64(MaxProgress))
+}
+
+// GetStatusCode Returns status code of the last response, zero if no response was received
+func (h *HTTPTask) GetStatusCode() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.status
+}
+
+// GetBody Returns response body of a request which is not a download
+func (h *HTTPTask) GetBody() []byte {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]byte(nil), h.response.Bytes()...)
+}
+
+// GetReceived Returns received bytes and expected total bytes of the response body, total is -1 if unknown
+// Note: For a resumed download both include the part downloaded in earlier runs
+func (h *HTTPTask) GetReceived() (int64, int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.received, h.total
+}
+
+// GetError Returns HTTPStatusError, checksum mismatch, transport error or cancel reason of the worker of last run
+func (h *HTTPTask) GetError() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Reset Resets task to start state, the part file of an interrupted download is kept to be resumed
+func (h *HTTPTask) Reset() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state == Running {
+		return ErrTaskRunning
+	}
+	h.state = Waiting
+	h.status = 0
+	h.received = 0
+	h.total = -1
+	h.response.Reset()
+	h.err = nil
+	return nil
+}
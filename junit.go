@@ -0,0 +1,106 @@
+package gotask
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// junitSuite JUnit testsuite element of a worker run
+type junitSuite struct {
+	XMLName   xml.Name    `xml:"testsuite"`
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+// junitCase JUnit testcase element of a task
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitProblem `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitProblem JUnit failure, error or skipped element
+type junitProblem struct {
+	Message string `xml:"message,attr,omitempty"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// ExportJUnit Writes completed run as JUnit XML testsuite with one testcase per task
+// Failed tasks are reported as failures, canceled or timed out tasks as errors and tasks not started or not selected as
+// skipped. The kept output of every task is written to its system-out element.
+func (w *Worker) ExportJUnit(out io.Writer) error {
+	w.mu.Lock()
+	report := w.report()
+	outputs := make([]*Output, len(w.taskQueue))
+	for idx, task := range w.taskQueue {
+		outputs[idx] = w.outputs[task]
+	}
+	w.mu.Unlock()
+	switch report.State {
+	case Waiting:
+		return ErrWorkerNotStarted
+	case Running:
+		return ErrWorkerRunning
+	}
+
+	suite := junitSuite{
+		Name:      report.Worker,
+		Tests:     len(report.Tasks),
+		Time:      junitSeconds(report.Duration),
+		Timestamp: report.Start.Format("2006-01-02T15:04:05"),
+	}
+	for idx, task := range report.Tasks {
+		testCase := junitCase{
+			Name:      task.Name,
+			ClassName: report.Worker,
+			Time:      junitSeconds(task.Duration),
+		}
+		switch task.State {
+		case Failed:
+			suite.Failures++
+			testCase.Failure = &junitProblem{Message: task.Error, Type: StateToString(task.State), Text: task.Error}
+		case Canceled, TimeoutReached:
+			suite.Errors++
+			testCase.Error = &junitProblem{Message: task.Error, Type: StateToString(task.State), Text: task.Error}
+		case Waiting:
+			suite.Skipped++
+			testCase.Skipped = &junitProblem{Message: "task was not started"}
+		case Skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitProblem{Message: task.Reason}
+		}
+		if output := outputs[idx]; output != nil {
+			testCase.SystemOut = output.String()
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// junitSeconds Formats duration in seconds as used by JUnit
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
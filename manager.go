@@ -0,0 +1,276 @@
+package gotask
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrManagerIDExists  error = errors.New("worker id already registered")
+	ErrManagerIDUnknown error = errors.New("worker id not registered")
+	ErrManagerShutdown  error = errors.New("manager was shut down")
+)
+
+// Manager Registers many workers by unique id and runs them with a global limit of concurrently running workers
+type Manager struct {
+	mu         sync.Mutex
+	maxRunning int // maximum amount of workers running at the same time, zero if unlimited
+	workers    map[string]*managedWorker
+	order      []string // ids in registration order
+	queue      []string // ids of started workers waiting for a free slot in start order
+	running    int
+	shutdown   bool
+	wg         sync.WaitGroup
+}
+
+// managedWorker Registered worker with the timeout it was started with
+type managedWorker struct {
+	worker  *Worker
+	timeout time.Duration
+	queued  bool
+	active  bool // worker was started by the manager and did not finish yet
+}
+
+// NewManager Factory method for creating a manager running at most maxRunning workers at once, zero or below is unlimited
+func NewManager(maxRunning int) *Manager {
+	manager := Manager{
+		maxRunning: maxRunning,
+		workers:    make(map[string]*managedWorker),
+	}
+	return &manager
+}
+
+// Register Registers worker under unique id
+func (m *Manager) Register(id string, worker *Worker) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		return ErrManagerShutdown
+	}
+	if _, ok := m.workers[id]; ok {
+		return ErrManagerIDExists
+	}
+	m.workers[id] = &managedWorker{worker: worker}
+	m.order = append(m.order, id)
+	return nil
+}
+
+// Unregister Removes worker from manager, workers which are queued or running can not be removed
+func (m *Manager) Unregister(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	managed, ok := m.workers[id]
+	if !ok {
+		return ErrManagerIDUnknown
+	}
+	if managed.active {
+		return ErrWorkerRunning
+	}
+	delete(m.workers, id)
+	for idx, other := range m.order {
+		if other == id {
+			m.order = append(m.order[:idx], m.order[idx+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Start Runs worker with timeout once less than the maximum amount of workers is running, queued workers start in order
+// Note: A queued worker which can not be run once a slot is free, e.g. as it already finished, is dropped from the queue
+func (m *Manager) Start(id string, timeout time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.shutdown {
+		return ErrManagerShutdown
+	}
+	managed, ok := m.workers[id]
+	if !ok {
+		return ErrManagerIDUnknown
+	}
+	if managed.active {
+		return ErrWorkerRunning
+	}
+
+	managed.timeout = timeout
+	if m.maxRunning > 0 && m.running >= m.maxRunning {
+		managed.active = true
+		managed.queued = true
+		m.queue = append(m.queue, id)
+		m.wg.Add(1)
+		return nil
+	}
+	if err := managed.worker.Run(timeout); err != nil {
+		return err
+	}
+	managed.active = true
+	m.wg.Add(1)
+	m.start(managed)
+	return nil
+}
+
+// Get Returns worker registered under id
+func (m *Manager) Get(id string) (*Worker, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	managed, ok := m.workers[id]
+	if !ok {
+		return nil, ErrManagerIDUnknown
+	}
+	return managed.worker, nil
+}
+
+// GetIDs Returns ids of all registered workers in registration order
+func (m *Manager) GetIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, len(m.order))
+	copy(ids, m.order)
+	return ids
+}
+
+// Filter Returns ids of all registered workers in state in registration order
+// Note: Queued workers are in state Waiting, use GetQueued to tell them apart from workers which were not started
+func (m *Manager) Filter(state State) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for _, id := range m.order {
+		if m.workers[id].worker.GetState() == state {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetQueued Returns ids of started workers waiting for a free slot in start order
+func (m *Manager) GetQueued() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make([]string, len(m.queue))
+	copy(ids, m.queue)
+	return ids
+}
+
+// GetAmountRunning Returns amount of workers started by the manager which are presently running
+func (m *Manager) GetAmountRunning() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.running
+}
+
+// GetTotalWorkLoad Returns total workload of all registered workers combined
+func (m *Manager) GetTotalWorkLoad() float64 {
+	totalLoad := 0.0
+	for _, worker := range m.snapshotWorkers() {
+		totalLoad += worker.GetTotalWorkLoad()
+	}
+	return totalLoad
+}
+
+// GetRemainingWorkLoad Returns remaining workload of all registered workers combined
+func (m *Manager) GetRemainingWorkLoad() float64 {
+	remainLoad := 0.0
+	for _, worker := range m.snapshotWorkers() {
+		remainLoad += worker.GetRemainingWorkLoad()
+	}
+	return remainLoad
+}
+
+// GetProgress Returns progress over all registered workers in percent from 0 to 100, weighted by their workload
+func (m *Manager) GetProgress() Progress {
+	workTotal := 0.0
+	workRemain := 0.0
+	for _, worker := range m.snapshotWorkers() {
+		workTotal += worker.GetTotalWorkLoad()
+		workRemain += worker.GetRemainingWorkLoad()
+	}
+	if workTotal == 0 {
+		return MinProgress
+	}
+	return Progress((workTotal-workRemain)/workTotal) * MaxProgress
+}
+
+// Wait Waits until all started and queued workers finished
+func (m *Manager) Wait() {
+	m.wg.Wait()
+}
+
+// Shutdown Drops all queued workers, stops all running workers and waits until they finished
+// Afterwards no more workers can be registered or started.
+func (m *Manager) Shutdown() error {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return ErrManagerShutdown
+	}
+	m.shutdown = true
+	for _, id := range m.queue {
+		managed := m.workers[id]
+		managed.queued = false
+		managed.active = false
+		m.wg.Done()
+	}
+	m.queue = nil
+	var running []*Worker
+	for _, id := range m.order {
+		if managed := m.workers[id]; managed.active {
+			running = append(running, managed.worker)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, worker := range running {
+		_ = worker.Stop()
+	}
+	m.wg.Wait()
+	return nil
+}
+
+// snapshotWorkers Returns all registered workers in registration order
+func (m *Manager) snapshotWorkers() []*Worker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	workers := make([]*Worker, 0, len(m.order))
+	for _, id := range m.order {
+		workers = append(workers, m.workers[id].worker)
+	}
+	return workers
+}
+
+// start Supervises already running worker in background, caller must hold the lock
+func (m *Manager) start(managed *managedWorker) {
+	managed.queued = false
+	m.running++
+	go m.supervise(managed)
+}
+
+// supervise Waits until worker finished and starts the next queued worker afterwards
+func (m *Manager) supervise(managed *managedWorker) {
+	if managed.worker.GetState() != Waiting { // a worker without tasks does not start at all
+		_ = managed.worker.Wait()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	managed.active = false
+	m.running--
+	m.wg.Done()
+	m.startQueued()
+}
+
+// startQueued Starts queued workers while slots are free, queued workers failing to start are dropped, caller must hold the lock
+func (m *Manager) startQueued() {
+	for !m.shutdown && len(m.queue) > 0 && (m.maxRunning <= 0 || m.running < m.maxRunning) {
+		next := m.workers[m.queue[0]]
+		m.queue = m.queue[1:]
+		if err := next.worker.Run(next.timeout); err != nil {
+			next.queued = false
+			next.active = false
+			m.wg.Done()
+			continue
+		}
+		m.start(next)
+	}
+}
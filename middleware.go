@@ -0,0 +1,103 @@
+package gotask
+
+import (
+	"fmt"
+	"time"
+)
+
+// RunFunc Runs the task of handle and returns its error
+type RunFunc func(h *Handle) error
+
+// Middleware Wraps the run of a task, similar to http.Handler middleware
+// A middleware gets the next RunFunc of the chain and returns a RunFunc, which can inspect the task via the handle,
+// run code around next, short-circuit by not calling next, modify the returned error or call next again to retry.
+// A task short-circuited without error is reported as Skipped. Only tasks which reached Finished count as done and are
+// compensated, also if a middleware returned an error afterwards. A task whose error was swallowed keeps its Failed
+// state without stopping the worker, its run counts as ended with the progress it reached, so the progress of the
+// worker stays below 100 percent although the worker finishes.
+type Middleware func(next RunFunc) RunFunc
+
+// Wrapped Optional interface for tasks carrying own middleware, which is run inside the middleware of the worker
+type Wrapped interface {
+	GetMiddleware() []Middleware // returns middleware of task, first one is the outermost
+}
+
+// Use Adds middleware wrapped around the run of every task, the first added middleware is the outermost
+// Note: Middleware of the worker wraps the middleware of the tasks, finally tasks are run without middleware
+func (w *Worker) Use(middleware ...Middleware) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.middleware = append(w.middleware, middleware...)
+	return nil
+}
+
+// chain Returns run of task wrapped in the middleware of worker and task, reached is set once the task was run
+// The error returned by the chain decides if the task failed, by default the error of Failable tasks is returned.
+func (w *Worker) chain(task Runnable, reached *bool) RunFunc {
+	run := func(h *Handle) error {
+		*reached = true
+		w.recordStart(task)
+		defer w.recordEnd(task)
+		task.Run()
+		if failable, ok := task.(Failable); ok {
+			return failable.GetError()
+		}
+		return nil
+	}
+
+	w.mu.Lock()
+	middleware := append([]Middleware(nil), w.middleware...)
+	w.mu.Unlock()
+	if wrapped, ok := task.(Wrapped); ok {
+		middleware = append(middleware, wrapped.GetMiddleware()...)
+	}
+	for idx := len(middleware) - 1; idx >= 0; idx-- {
+		run = middleware[idx](run)
+	}
+	return run
+}
+
+// bypass Marks task short-circuited by middleware as skipped, caller must hold the worker lock
+func (w *Worker) bypass(task Runnable) {
+	if w.bypassed == nil {
+		w.bypassed = make(map[Runnable]struct{})
+	}
+	w.bypassed[task] = struct{}{}
+	w.book.drop(task)
+}
+
+// Retry Middleware running a failed task again up to attempts times in total with delay in between
+// No more attempts are made once the worker was stopped or timed out.
+func Retry(attempts int, delay time.Duration) Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(h *Handle) error {
+			err := next(h)
+			for attempt := 1; attempt < attempts && err != nil; attempt++ {
+				select {
+				case <-h.Done():
+					return err
+				case <-h.after(delay):
+				}
+				err = next(h)
+			}
+			return err
+		}
+	}
+}
+
+// Recover Middleware turning a panic of the task or of middleware inside of it into an error wrapping ErrTaskPanicked
+func Recover() Middleware {
+	return func(next RunFunc) RunFunc {
+		return func(h *Handle) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%w: %v", ErrTaskPanicked, r)
+				}
+			}()
+			return next(h)
+		}
+	}
+}
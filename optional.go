@@ -0,0 +1,71 @@
+package gotask
+
+import "time"
+
+// Optional Optional interface for tasks which can be skipped if they can not finish before the timeout
+type Optional interface {
+	IsOptional() bool // returns true if task is optional
+}
+
+// SetSkipOptional Enables skipping optional tasks which can not finish before the worker or stage timeout is reached
+// Before an optional task is started, its estimated duration is compared with the remaining time. The estimate is the
+// duration of the last successful run of a task with the same name in this worker, one second per weight unit if the
+// task did not finish yet. Skipped tasks are reported as Skipped.
+func (w *Worker) SetSkipOptional(enabled bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.skipOptional = enabled
+	return nil
+}
+
+// GetEstimate Returns estimated duration of task, see SetSkipOptional
+func (w *Worker) GetEstimate(task Runnable) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.estimate(task)
+}
+
+// estimate Unlocked version of GetEstimate, caller must hold the worker lock
+func (w *Worker) estimate(task Runnable) time.Duration {
+	if duration, ok := w.history[task.GetName()]; ok {
+		return duration
+	}
+	return weightDuration(task.GetWeight())
+}
+
+// isOptional Checks if task is optional
+func isOptional(task Runnable) bool {
+	optional, ok := task.(Optional)
+	return ok && optional.IsOptional()
+}
+
+// skipUnfit Marks task as skipped if it is optional and can not finish before deadline, caller must hold the worker lock
+// Returns true if task was skipped
+func (w *Worker) skipUnfit(task Runnable, deadline time.Time) bool {
+	if !w.skipOptional || deadline.IsZero() || !isOptional(task) {
+		return false
+	}
+	if w.estimate(task) <= deadline.Sub(w.clock.Now()) {
+		return false
+	}
+	if w.unfit == nil {
+		w.unfit = make(map[Runnable]struct{})
+	}
+	w.unfit[task] = struct{}{}
+	w.book.remove(task)
+	return true
+}
+
+// recordHistory Records duration of task as estimate of later runs if it finished, caller must hold the worker lock
+func (w *Worker) recordHistory(task Runnable, duration time.Duration) {
+	if task.GetState() != Finished {
+		return
+	}
+	if w.history == nil {
+		w.history = make(map[string]time.Duration)
+	}
+	w.history[task.GetName()] = duration
+}
@@ -0,0 +1,303 @@
+package gotask
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// DefaultOutputLimit Default amount of bytes kept of the output of every task
+const DefaultOutputLimit = 64 * 1024
+
+// Output Output sink of one task run, which keeps the last written bytes in a bounded ring buffer
+// Written output is passed on to live subscribers and optionally teed to a file. Subscribers which do not keep up miss
+// chunks instead of blocking the task.
+type Output struct {
+	mu      sync.Mutex
+	task    string
+	limit   int    // maximum amount of bytes kept
+	ring    []byte // ring buffer of limit bytes, allocated on first write
+	start   int    // position of oldest byte in ring
+	size    int    // amount of bytes in ring
+	dropped int64  // amount of bytes dropped from the front as the limit was reached
+	file    *os.File
+	fileErr error // error creating the file output is teed to, nil if teed or not teed at all
+	subs    map[chan []byte]struct{}
+	stream  *outputStream // stream of the worker, nil if task was run without a worker
+	closed  bool
+}
+
+// OutputChunk Chunk of output written by a task, received by subscribers of the worker output
+type OutputChunk struct {
+	Task string // name of task
+	Data []byte
+}
+
+// outputStream Passes output chunks of all tasks of a worker to its subscribers
+type outputStream struct {
+	mu   sync.Mutex
+	subs map[chan OutputChunk]struct{}
+}
+
+// newOutput Creates output of task keeping limit bytes, which is teed to file if file is not nil
+func newOutput(task string, limit int, file *os.File, fileErr error, stream *outputStream) *Output {
+	if limit < 1 {
+		limit = 1
+	}
+	output := Output{
+		task:    task,
+		limit:   limit,
+		file:    file,
+		fileErr: fileErr,
+		stream:  stream,
+	}
+	return &output
+}
+
+// Write Stores p in ring buffer and passes it on to file and subscribers
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	limit := o.limit
+	if o.ring == nil && len(p) > 0 {
+		o.ring = make([]byte, limit)
+	}
+	data := p
+	if len(data) > limit {
+		o.dropped += int64(len(data) - limit)
+		data = data[len(data)-limit:]
+	}
+	for _, b := range data {
+		if o.size == limit {
+			o.start = (o.start + 1) % limit
+			o.size--
+			o.dropped++
+		}
+		o.ring[(o.start+o.size)%limit] = b
+		o.size++
+	}
+
+	if o.closed || len(p) == 0 {
+		return len(p), nil
+	}
+	if o.file != nil {
+		if _, err := o.file.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	chunk := append([]byte(nil), p...)
+	for sub := range o.subs {
+		select {
+		case sub <- chunk:
+		default:
+		}
+	}
+	if o.stream != nil {
+		o.stream.publish(OutputChunk{Task: o.task, Data: chunk})
+	}
+	return len(p), nil
+}
+
+// Bytes Returns copy of kept output
+func (o *Output) Bytes() []byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data := make([]byte, o.size)
+	for idx := 0; idx < o.size; idx++ {
+		data[idx] = o.ring[(o.start+idx)%len(o.ring)]
+	}
+	return data
+}
+
+// String Returns kept output as string
+func (o *Output) String() string {
+	return string(o.Bytes())
+}
+
+// GetDropped Returns amount of bytes dropped from the front as the limit was reached
+func (o *Output) GetDropped() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.dropped
+}
+
+// GetFileError Returns error creating the file the output is teed to, nil if the output is teed or not teed at all
+func (o *Output) GetFileError() error {
+	return o.fileErr
+}
+
+// Subscribe Returns channel receiving all output written from now on with room for buffer chunks and a function to
+// cancel the subscription. The channel is closed once the task run completed or the subscription was canceled.
+func (o *Output) Subscribe(buffer int) (<-chan []byte, func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sub := make(chan []byte, buffer)
+	if o.closed {
+		close(sub)
+		return sub, func() {}
+	}
+	if o.subs == nil {
+		o.subs = make(map[chan []byte]struct{})
+	}
+	o.subs[sub] = struct{}{}
+	cancel := func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if _, ok := o.subs[sub]; ok {
+			delete(o.subs, sub)
+			close(sub)
+		}
+	}
+	return sub, cancel
+}
+
+// close Closes file and subscriptions once the task run completed, later output is only kept in the ring buffer
+func (o *Output) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	if o.file != nil {
+		_ = o.file.Close()
+	}
+	for sub := range o.subs {
+		delete(o.subs, sub)
+		close(sub)
+	}
+}
+
+// publish Passes chunk on to all subscribers without blocking
+func (s *outputStream) publish(chunk OutputChunk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		select {
+		case sub <- chunk:
+		default:
+		}
+	}
+}
+
+// SetOutputLimit Sets amount of bytes kept of the output of every task, default is DefaultOutputLimit
+func (w *Worker) SetOutputLimit(limit int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.outputLimit = limit
+	return nil
+}
+
+// SetOutputDir Sets directory the output of every task is teed to, in a file named after the task. Set empty to disable.
+// Tasks whose names map to the same file name get a counter suffix, e.g. "build-2.log".
+func (w *Worker) SetOutputDir(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.outputDir = dir
+	return nil
+}
+
+// GetOutput Returns output of the present or last run of task, nil if the task was not run yet
+func (w *Worker) GetOutput(task Runnable) *Output {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.outputs[task]
+}
+
+// SubscribeOutput Returns channel receiving the output of all tasks written from now on with room for buffer chunks
+// and a function to cancel the subscription. Subscribers which do not keep up miss chunks instead of blocking tasks.
+func (w *Worker) SubscribeOutput(buffer int) (<-chan OutputChunk, func()) {
+	stream := w.outputStream()
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	sub := make(chan OutputChunk, buffer)
+	stream.subs[sub] = struct{}{}
+	cancel := func() {
+		stream.mu.Lock()
+		defer stream.mu.Unlock()
+		if _, ok := stream.subs[sub]; ok {
+			delete(stream.subs, sub)
+			close(sub)
+		}
+	}
+	return sub, cancel
+}
+
+// outputStream Returns output stream of worker and creates it if not existing
+func (w *Worker) outputStream() *outputStream {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stream == nil {
+		w.stream = &outputStream{subs: make(map[chan OutputChunk]struct{})}
+	}
+	return w.stream
+}
+
+// unsafeFileChars Matches characters which are replaced in output file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// newTaskOutput Creates output for a run of task, the output of an earlier run of the task is replaced
+// Note: If the output file can not be created, the output is only kept in memory and the error is returned by
+// GetFileError of the output
+func (w *Worker) newTaskOutput(task Runnable) *Output {
+	stream := w.outputStream()
+	w.mu.Lock()
+	limit, dir := w.outputLimit, w.outputDir
+	name := ""
+	if dir != "" {
+		name = w.outputFileName(task)
+	}
+	w.mu.Unlock()
+	if limit == 0 {
+		limit = DefaultOutputLimit
+	}
+	var file *os.File
+	var fileErr error
+	if dir != "" {
+		file, fileErr = os.Create(filepath.Join(dir, name))
+		if fileErr != nil {
+			file = nil
+		}
+	}
+
+	output := newOutput(task.GetName(), limit, file, fileErr, stream)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.outputs == nil {
+		w.outputs = make(map[Runnable]*Output)
+	}
+	w.outputs[task] = output
+	return output
+}
+
+// outputFileName Returns name of the file the output of task is teed to, caller must hold the worker lock
+// The name is assigned on first use and kept for later runs of the task. Tasks whose names map to a name already
+// assigned to another task get a counter suffix, so they do not overwrite each other.
+func (w *Worker) outputFileName(task Runnable) string {
+	if name, ok := w.outputFiles[task]; ok {
+		return name
+	}
+	if w.outputFiles == nil {
+		w.outputFiles = make(map[Runnable]string)
+		w.outputNames = make(map[string]struct{})
+	}
+	base := unsafeFileChars.ReplaceAllString(task.GetName(), "_")
+	name := base + ".log"
+	for idx := 2; ; idx++ {
+		if _, used := w.outputNames[name]; !used {
+			break
+		}
+		name = fmt.Sprintf("%s-%d.log", base, idx)
+	}
+	w.outputFiles[task] = name
+	w.outputNames[name] = struct{}{}
+	return name
+}
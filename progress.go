@@ -0,0 +1,88 @@
+package gotask
+
+// workBook Incremental bookkeeping of the work of the selected tasks of a worker, guarded by the worker lock
+// Completed task runs are accounted once when they end, so the progress is computed from the few running tasks only
+// instead of iterating the whole task queue. The book is recounted whenever tasks may have changed outside of a run,
+// i.e. at Run, Reset and on selection changes.
+type workBook struct {
+	total    float64               // weight of all selected tasks
+	tasks    int                   // amount of selected tasks
+	done     float64               // work of task runs which ended (progress times weight)
+	finished int                   // amount of selected tasks which ended with state Finished
+	active   map[Runnable]struct{} // tasks presently running
+}
+
+// add Accounts task added to the selected tasks
+func (b *workBook) add(task Runnable) {
+	b.pending(task)
+	b.account(task)
+}
+
+// pending Accounts task added to the selected tasks without any work done, as it is about to be run
+func (b *workBook) pending(task Runnable) {
+	b.total += float64(task.GetWeight())
+	b.tasks++
+}
+
+// remove Removes task which was not started from the selected tasks
+func (b *workBook) remove(task Runnable) {
+	b.total -= float64(task.GetWeight())
+	b.tasks--
+}
+
+// start Marks task as running
+func (b *workBook) start(task Runnable) {
+	if b.active == nil {
+		b.active = make(map[Runnable]struct{})
+	}
+	b.active[task] = struct{}{}
+}
+
+// end Accounts work of task whose run ended
+func (b *workBook) end(task Runnable) {
+	delete(b.active, task)
+	b.account(task)
+}
+
+// drop Removes task whose run ended without its work counting from the selected tasks
+func (b *workBook) drop(task Runnable) {
+	delete(b.active, task)
+	b.remove(task)
+}
+
+// account Adds work done by task
+func (b *workBook) account(task Runnable) {
+	b.done += float64(task.GetProgress()) / float64(MaxProgress) * float64(task.GetWeight())
+	if task.GetState() == Finished {
+		b.finished++
+	}
+}
+
+// workDone Returns work of ended and running task runs
+func (b *workBook) workDone() float64 {
+	if b.finished == b.tasks {
+		return b.total // avoids rounding errors once all tasks finished
+	}
+	done := b.done
+	for task := range b.active {
+		done += float64(task.GetProgress()) / float64(MaxProgress) * float64(task.GetWeight())
+	}
+	return done
+}
+
+// recount Accounts all selected tasks of worker from scratch, caller must hold the worker lock
+// If starting, only tasks kept from the last run are accounted with their work, all others are run again and start
+// without work, even if they finished in an earlier run.
+func (w *Worker) recount(starting bool) {
+	w.book = workBook{}
+	for _, task := range w.taskQueue {
+		if w.isSkipped(task) {
+			continue
+		}
+		if _, kept := w.kept[task]; starting && !kept {
+			w.book.pending(task)
+			continue
+		}
+		w.book.add(task)
+	}
+}
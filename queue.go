@@ -0,0 +1,460 @@
+package gotask
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueClosed          error = errors.New("queue already closed")
+	ErrQueueCorrupt         error = errors.New("queue journal corrupt")
+	ErrQueueItemUnknown     error = errors.New("queue item unknown")
+	ErrQueueItemNotInFlight error = errors.New("queue item not in flight")
+	ErrQueueItemNotDead     error = errors.New("queue item not in dead letter list")
+	ErrQueueReceiptStale    error = errors.New("queue receipt stale, item was delivered again")
+)
+
+// DiskQueue Durable queue of serializable task definitions backed by an append-only journal file
+// Every change is appended to the journal and synced before it is applied, so queued, in flight, completed and dead
+// items survive restarts. Items are delivered at least once: an item popped but not acknowledged within its visibility
+// timeout, e.g. as the process crashed, is delivered again. Items failing too often are moved to the dead letter list.
+type DiskQueue struct {
+	mu          sync.Mutex
+	path        string
+	file        *os.File
+	maxAttempts int
+	items       map[string]*queueItem
+	order       []*queueItem // all items in push order
+	nextID      uint64
+	clock       Clock // source of time for visibility timeouts
+}
+
+// queueItem Item of the queue with its delivery state
+// The state is Waiting while queued, Running while in flight, Finished once acknowledged and Failed once dead.
+type queueItem struct {
+	spec       TaskSpec
+	state      State
+	attempts   int
+	deliveries int       // amount of deliveries since push, unlike attempts not reset by retry
+	visibleAt  time.Time // time an in flight item is delivered again
+	lastErr    string
+}
+
+// QueueItem Information about an item of the queue
+type QueueItem struct {
+	Spec      TaskSpec  // task definition, Spec.ID is the id of the item
+	State     State     // Waiting, Running (in flight), Finished (acknowledged) or Failed (dead letter)
+	Attempts  int       // amount of deliveries
+	VisibleAt time.Time // time an in flight item is delivered again
+	LastError string    // error of last failed attempt
+	Receipt   Receipt   // receipt of the delivery, only set for items returned by Pop
+}
+
+// Receipt Identifies one delivery of an item, Ack and Nack only accept the receipt of the latest delivery
+// So a consumer whose visibility timeout expired can not acknowledge or fail the item delivered to another consumer.
+type Receipt struct {
+	ID       string // id of the item
+	Delivery int    // number of the delivery since the item was pushed
+}
+
+// queueRecord One line of the journal
+type queueRecord struct {
+	Op         string     `json:"op"` // push, pop, ack, nack, dead, retry or restore
+	ID         string     `json:"id"`
+	Spec       *TaskSpec  `json:"spec,omitempty"`
+	Until      *time.Time `json:"until,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	State      State      `json:"state,omitempty"`      // only used by restore records written by Compact
+	Attempts   int        `json:"attempts,omitempty"`   // only used by restore records written by Compact
+	Deliveries int        `json:"deliveries,omitempty"` // only used by restore records written by Compact
+}
+
+// OpenDiskQueue Opens queue journal at path and restores all items, the file is created if not existing
+// Items are moved to the dead letter list after five failed attempts by default.
+func OpenDiskQueue(path string) (*DiskQueue, error) {
+	queue := DiskQueue{
+		path:        path,
+		maxAttempts: 5,
+		items:       make(map[string]*queueItem),
+		clock:       SystemClock,
+	}
+	if err := queue.replay(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	queue.file = file
+	return &queue, nil
+}
+
+// SetMaxAttempts Sets amount of deliveries after which a failing item is moved to the dead letter list, minimum is one
+func (q *DiskQueue) SetMaxAttempts(maxAttempts int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	q.maxAttempts = maxAttempts
+}
+
+// SetClock Sets clock used for visibility timeouts, default is SystemClock
+func (q *DiskQueue) SetClock(clock Clock) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clock = clock
+}
+
+// Push Appends task definition to the queue and returns the id of the new item
+func (q *DiskQueue) Push(spec TaskSpec) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	spec.ID = strconv.FormatUint(q.nextID+1, 10)
+	if err := q.write(queueRecord{Op: "push", ID: spec.ID, Spec: &spec}); err != nil {
+		return "", err
+	}
+	return spec.ID, nil
+}
+
+// Pop Delivers next queued item and keeps it in flight for the visibility timeout, nil if no item is available
+// In flight items whose visibility timeout expired are delivered again, or moved to the dead letter list if they
+// reached the maximum amount of attempts. The receipt of the returned item has to be passed to Ack or Nack.
+func (q *DiskQueue) Pop(visibility time.Duration) (*QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.clock.Now()
+	for _, item := range q.order {
+		expired := item.state == Running && !now.Before(item.visibleAt)
+		if expired && item.attempts >= q.maxAttempts {
+			if err := q.write(queueRecord{Op: "dead", ID: item.spec.ID, Error: "visibility timeout expired"}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if item.state != Waiting && !expired {
+			continue
+		}
+		until := now.Add(visibility)
+		if err := q.write(queueRecord{Op: "pop", ID: item.spec.ID, Until: &until}); err != nil {
+			return nil, err
+		}
+		info := item.info()
+		info.Receipt = Receipt{ID: item.spec.ID, Delivery: item.deliveries}
+		return &info, nil
+	}
+	return nil, nil
+}
+
+// Ack Acknowledges that in flight item of receipt completed
+// Returns ErrQueueReceiptStale if the item was delivered again meanwhile
+func (q *DiskQueue) Ack(receipt Receipt) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.inFlight(receipt); err != nil {
+		return err
+	}
+	return q.write(queueRecord{Op: "ack", ID: receipt.ID})
+}
+
+// Nack Reports that in flight item of receipt failed, the item is queued again or moved to the dead letter list if it
+// reached the maximum amount of attempts. Returns ErrQueueReceiptStale if the item was delivered again meanwhile.
+func (q *DiskQueue) Nack(receipt Receipt, failure error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, err := q.inFlight(receipt)
+	if err != nil {
+		return err
+	}
+	id := receipt.ID
+	msg := ""
+	if failure != nil {
+		msg = failure.Error()
+	}
+	if item.attempts >= q.maxAttempts {
+		return q.write(queueRecord{Op: "dead", ID: id, Error: msg})
+	}
+	return q.write(queueRecord{Op: "nack", ID: id, Error: msg})
+}
+
+// Retry Queues item of the dead letter list again with reset attempts
+func (q *DiskQueue) Retry(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, ok := q.items[id]
+	if !ok {
+		return ErrQueueItemUnknown
+	}
+	if item.state != Failed {
+		return ErrQueueItemNotDead
+	}
+	return q.write(queueRecord{Op: "retry", ID: id})
+}
+
+// Get Returns information about item
+func (q *DiskQueue) Get(id string) (QueueItem, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item, ok := q.items[id]
+	if !ok {
+		return QueueItem{}, ErrQueueItemUnknown
+	}
+	return item.info(), nil
+}
+
+// GetItems Returns all items in state in push order, e.g. Failed for the dead letter list
+func (q *DiskQueue) GetItems(state State) []QueueItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var items []QueueItem
+	for _, item := range q.order {
+		if item.state == state {
+			items = append(items, item.info())
+		}
+	}
+	return items
+}
+
+// GetDeadLetters Returns all items which failed too often in push order
+func (q *DiskQueue) GetDeadLetters() []QueueItem {
+	return q.GetItems(Failed)
+}
+
+// Len Returns amount of items which are queued or in flight
+func (q *DiskQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	amount := 0
+	for _, item := range q.order {
+		if item.state == Waiting || item.state == Running {
+			amount++
+		}
+	}
+	return amount
+}
+
+// Compact Rewrites journal with only the present state of all items, so it does not grow forever
+// The new journal is written to a temporary file and renamed, so a crash leaves either the old or the new journal.
+func (q *DiskQueue) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return ErrQueueClosed
+	}
+
+	tmpPath := q.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, item := range q.order {
+		if err := encoder.Encode(item.record()); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		return err
+	}
+
+	q.file.Close()
+	q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// Close Closes journal file, the queue can be opened again with OpenDiskQueue
+func (q *DiskQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.file == nil {
+		return ErrQueueClosed
+	}
+	err := q.file.Close()
+	q.file = nil
+	return err
+}
+
+// Tasks Pops up to max items and returns them as tasks running target, which acknowledge the item if target succeeds
+// and report the failure otherwise. Items of tasks which never run are delivered again after the visibility timeout.
+func (q *DiskQueue) Tasks(max int, visibility time.Duration, target func(spec TaskSpec) error) ([]Runnable, error) {
+	var tasks []Runnable
+	for len(tasks) < max {
+		item, err := q.Pop(visibility)
+		if err != nil {
+			return tasks, err
+		}
+		if item == nil {
+			break
+		}
+		receipt := item.Receipt
+		tasks = append(tasks, NewTask(item.Spec.Name, Weight(1), item.Spec.Kind, func(arg interface{}) error {
+			if err := target(arg.(TaskSpec)); err != nil {
+				_ = q.Nack(receipt, err)
+				return err
+			}
+			// an item whose visibility timeout expired meanwhile is already dead or delivered again, the work is done anyway
+			err := q.Ack(receipt)
+			if err != nil && !errors.Is(err, ErrQueueItemNotInFlight) && !errors.Is(err, ErrQueueReceiptStale) {
+				return err
+			}
+			return nil
+		}, item.Spec))
+	}
+	return tasks, nil
+}
+
+// write Appends record to the journal, syncs it and applies it afterwards, caller must hold the lock
+func (q *DiskQueue) write(record queueRecord) error {
+	if q.file == nil {
+		return ErrQueueClosed
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if err := q.file.Sync(); err != nil {
+		return err
+	}
+	return q.apply(record)
+}
+
+// replay Restores all items from the journal
+// A torn last line of a crashed write is cut off, so later records are not appended to it.
+func (q *DiskQueue) replay() error {
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	valid := int64(0) // length of journal up to the end of the last complete line
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) == 0 {
+				return nil
+			}
+			return os.Truncate(q.path, valid)
+		}
+		if err != nil {
+			return err
+		}
+		var record queueRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrQueueCorrupt, lineNo, err)
+		}
+		if err := q.apply(record); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrQueueCorrupt, lineNo, err)
+		}
+		valid += int64(len(line))
+	}
+}
+
+// apply Applies journal record to the items, caller must hold the lock
+func (q *DiskQueue) apply(record queueRecord) error {
+	if record.Op == "push" || record.Op == "restore" {
+		if record.Spec == nil {
+			return ErrQueueCorrupt
+		}
+		item := &queueItem{spec: *record.Spec, state: Waiting}
+		if record.Op == "restore" {
+			item.state = record.State
+			item.attempts = record.Attempts
+			item.deliveries = record.Deliveries
+			item.lastErr = record.Error
+			if record.Until != nil {
+				item.visibleAt = *record.Until
+			}
+		}
+		q.items[record.ID] = item
+		q.order = append(q.order, item)
+		if id, err := strconv.ParseUint(record.ID, 10, 64); err == nil && id > q.nextID {
+			q.nextID = id
+		}
+		return nil
+	}
+
+	item, ok := q.items[record.ID]
+	if !ok {
+		return ErrQueueItemUnknown
+	}
+	switch record.Op {
+	case "pop":
+		if record.Until == nil {
+			return ErrQueueCorrupt
+		}
+		item.state = Running
+		item.attempts++
+		item.deliveries++
+		item.visibleAt = *record.Until
+	case "ack":
+		item.state = Finished
+	case "nack":
+		item.state = Waiting
+		item.lastErr = record.Error
+	case "dead":
+		item.state = Failed
+		item.lastErr = record.Error
+	case "retry":
+		item.state = Waiting
+		item.attempts = 0
+	default:
+		return fmt.Errorf("%w: unknown operation '%s'", ErrQueueCorrupt, record.Op)
+	}
+	return nil
+}
+
+// inFlight Returns item of receipt if it is in flight with the delivery of receipt, caller must hold the lock
+func (q *DiskQueue) inFlight(receipt Receipt) (*queueItem, error) {
+	item, ok := q.items[receipt.ID]
+	if !ok {
+		return nil, ErrQueueItemUnknown
+	}
+	if item.state != Running {
+		return nil, ErrQueueItemNotInFlight
+	}
+	if item.deliveries != receipt.Delivery {
+		return nil, ErrQueueReceiptStale
+	}
+	return item, nil
+}
+
+// info Returns copy of item information
+func (i *queueItem) info() QueueItem {
+	return QueueItem{Spec: i.spec, State: i.state, Attempts: i.attempts, VisibleAt: i.visibleAt, LastError: i.lastErr}
+}
+
+// record Returns journal record restoring the present state of item at once
+func (i *queueItem) record() queueRecord {
+	spec := i.spec
+	until := i.visibleAt
+	return queueRecord{Op: "restore", ID: spec.ID, Spec: &spec, Until: &until, Error: i.lastErr, State: i.state, Attempts: i.attempts,
+		Deliveries: i.deliveries}
+}
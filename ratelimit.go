@@ -0,0 +1,140 @@
+package gotask
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter Token bucket limiting how many tasks are started per second, can be shared between multiple workers
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64 // maximum amount of tokens in bucket
+	tokens float64 // tokens currently available
+	last   time.Time
+	clock  Clock // source of time for refilling the bucket
+}
+
+// NewRateLimiter Factory method for creating a rate limiter allowing rate task starts per second with bursts of burst tasks
+// Note: A rate of zero or below does not limit at all, a burst below one is set to one. The bucket starts full.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		clock:  SystemClock,
+	}
+	return &limiter
+}
+
+// GetRate Returns amount of task starts allowed per second
+func (l *RateLimiter) GetRate() float64 {
+	return l.rate
+}
+
+// GetBurst Returns maximum amount of task starts allowed at once
+func (l *RateLimiter) GetBurst() int {
+	return int(l.burst)
+}
+
+// SetClock Sets clock used for refilling the bucket, default is SystemClock. Returns limiter for chaining.
+// Note: Should be the clock of the workers using the limiter, as they wait for tokens on their own clock
+func (l *RateLimiter) SetClock(clock Clock) *RateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = clock
+	l.last = time.Time{}
+	return l
+}
+
+// Allow Takes a token if one is available without waiting
+func (l *RateLimiter) Allow() bool {
+	return l.take() == 0
+}
+
+// take Takes a token if available and returns zero, otherwise returns time until next token is available
+func (l *RateLimiter) take() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	if wait <= 0 {
+		wait = time.Nanosecond
+	}
+	return wait
+}
+
+// throttle Waits until the worker rate limiter and the rate limiters of all task tags allow to start the task
+// Returns ErrWorkerCanceledByUser or the deadline error of the scope if the worker was stopped or timed out while waiting
+func (w *Worker) throttle(task Runnable, scope runScope) error {
+	w.mu.Lock()
+	var limiters []*RateLimiter
+	var reasons []string
+	if w.limiter != nil {
+		limiters = append(limiters, w.limiter)
+		reasons = append(reasons, "rate limit")
+	}
+	if tagged, ok := task.(Tagged); ok && len(w.tagLimiters) > 0 {
+		for _, tag := range tagged.GetTags() {
+			if limiter, ok := w.tagLimiters[tag]; ok {
+				limiters = append(limiters, limiter)
+				reasons = append(reasons, "rate limit: "+tag)
+			}
+		}
+	}
+	w.mu.Unlock()
+
+	for i, limiter := range limiters {
+		if err := w.waitForLimiter(task, limiter, reasons[i], scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForLimiter Waits until limiter grants a token to task while honouring stop and deadline of the scope
+func (w *Worker) waitForLimiter(task Runnable, limiter *RateLimiter, reason string, scope runScope) error {
+	defer w.setWaitingFor(task, "")
+
+	for {
+		delay := limiter.take()
+		if delay <= 0 {
+			return nil
+		}
+		if !scope.deadline.IsZero() {
+			if untilDeadline := scope.deadline.Sub(scope.clock.Now()); untilDeadline < delay {
+				delay = untilDeadline
+			}
+		}
+
+		w.setWaitingFor(task, reason)
+
+		select {
+		case <-w.quit:
+			return ErrWorkerCanceledByUser
+		case <-scope.clock.After(delay):
+		}
+		if scope.expired() {
+			return scope.deadlineErr
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package gotask
+
+import (
+	"sync"
+	"time"
+)
+
+// RemoteTask Task which is run by an agent of a broker instead of the worker itself
+// The worker waits for the result while the progress reported by the agent is reflected in the worker progress.
+// If the agent disconnects, the task is re-queued and run by the next polling agent.
+type RemoteTask struct {
+	mu      sync.Mutex
+	name    string
+	desc    string
+	weight  Weight
+	broker  *MemoryBroker
+	kind    string
+	payload []byte
+	state   State
+	job     *brokerJob // job of present or last run
+	result  []byte
+	err     error
+	handle  *Handle
+}
+
+// NewRemoteTask Factory method for creating a new task run by an agent of broker with the handler registered for kind
+func NewRemoteTask(name string, weight Weight, desc string, broker *MemoryBroker, kind string, payload []byte) *RemoteTask {
+	task := RemoteTask{
+		name:    name,
+		desc:    desc,
+		weight:  weight,
+		broker:  broker,
+		kind:    kind,
+		payload: payload,
+		state:   Waiting,
+	}
+	return &task
+}
+
+// Run Submits task to broker and waits for its result, this is called by worker
+// The task is withdrawn from the broker once the worker was stopped or timed out.
+func (r *RemoteTask) Run() {
+	r.mu.Lock()
+	r.state = Running
+	r.result = nil
+	r.err = nil
+	r.job = r.broker.submit(TaskSpec{Name: r.name, Kind: r.kind, Payload: r.payload})
+	job, handle := r.job, r.handle
+	r.mu.Unlock()
+
+	var done <-chan struct{}
+	if handle != nil {
+		done = handle.Done()
+	}
+
+	// agents are only dropped while someone looks at the broker, so a silent agent is detected while waiting
+	sweep := time.NewTicker(r.broker.sweep() / 2)
+	defer sweep.Stop()
+	for {
+		select {
+		case <-job.done:
+			result, err := r.broker.result(job)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.result = result
+			r.err = err
+			if err != nil {
+				r.state = Failed
+				return
+			}
+			r.state = Finished
+			return
+		case <-done:
+			r.broker.cancel(job)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.state = Canceled
+			r.err = handle.Err()
+			return
+		case <-sweep.C:
+			r.broker.sweep()
+		}
+	}
+}
+
+// bind Stores handle of worker which is about to run the task
+func (r *RemoteTask) bind(h *Handle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handle = h
+}
+
+// GetName Returns task name
+func (r *RemoteTask) GetName() string {
+	return r.name
+}
+
+// GetState Returns task state
+func (r *RemoteTask) GetState() State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// GetProgress Returns progress last reported by the agent running the task
+func (r *RemoteTask) GetProgress() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == Finished {
+		return MaxProgress
+	}
+	if r.state != Running {
+		return MinProgress
+	}
+	progress, _ := r.broker.status(r.job)
+	return progress
+}
+
+// GetAgent Returns name of agent running the task, empty if the task waits for an agent or is not running
+func (r *RemoteTask) GetAgent() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != Running {
+		return ""
+	}
+	_, agent := r.broker.status(r.job)
+	return agent
+}
+
+// GetWeight Returns task weight
+func (r *RemoteTask) GetWeight() Weight {
+	return r.weight
+}
+
+// GetDesc Returns task description
+func (r *RemoteTask) GetDesc() string {
+	return r.desc
+}
+
+// GetWorkLoad Returns task workload (progress times weight)
+func (r *RemoteTask) GetWorkLoad() int {
+	return int(float64(r.GetProgress()) * float64(r.weight) / float64(MaxProgress))
+}
+
+// GetResult Returns result sent by the agent in the last successful run
+func (r *RemoteTask) GetResult() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.result
+}
+
+// GetError Returns error reported by the agent or the cancel reason of the worker, nil if successful
+func (r *RemoteTask) GetError() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Reset Resets task to start state
+func (r *RemoteTask) Reset() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == Running {
+		return ErrTaskRunning
+	}
+	r.state = Waiting
+	r.job = nil
+	r.result = nil
+	r.err = nil
+	return nil
+}
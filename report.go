@@ -0,0 +1,176 @@
+package gotask
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report Summary of a worker run with timing and outcome of every task
+type Report struct {
+	Worker   string        `json:"worker"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`      // zero while the worker is running
+	Duration time.Duration `json:"duration"` // duration of the run in nanoseconds, up to now while running
+	State    State         `json:"state"`
+	Error    string        `json:"error,omitempty"` // error the run ended with
+	Tasks    []TaskSummary `json:"tasks"`
+}
+
+// TaskSummary Timing and outcome of one task of a worker run
+type TaskSummary struct {
+	Name     string        `json:"name"`
+	Desc     string        `json:"desc"`
+	Weight   Weight        `json:"weight"`
+	Start    time.Time     `json:"start"` // zero if the task was not started
+	End      time.Time     `json:"end"`   // zero if the task was not started or is running
+	Duration time.Duration `json:"duration"`
+	Attempts int           `json:"attempts"` // amount of times the task was started since the worker was reset
+	State    State         `json:"state"`
+	Error    string        `json:"error,omitempty"`  // error of failed tasks implementing Failable
+	Reason   string        `json:"reason,omitempty"` // why the task was skipped, empty if it was not skipped
+}
+
+// taskRun Timing of the present or last run of a task
+type taskRun struct {
+	start    time.Time
+	end      time.Time
+	attempts int
+}
+
+// GetReport Returns report of present or last run, tasks are listed in queue order
+func (w *Worker) GetReport() Report {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.report()
+}
+
+// report Unlocked version of GetReport, caller must hold the worker lock
+func (w *Worker) report() Report {
+	report := Report{
+		Worker: w.name,
+		State:  w.state,
+		Tasks:  make([]TaskSummary, 0, len(w.taskQueue)),
+	}
+	if w.state != Waiting {
+		report.Start = w.startTime
+		report.End = w.endTime
+		report.Duration = durationBetween(w.startTime, w.endTime, w.clock.Now())
+	}
+	if w.err != nil {
+		report.Error = w.err.Error()
+	}
+
+	for _, task := range w.taskQueue {
+		summary := TaskSummary{
+			Name:   task.GetName(),
+			Desc:   task.GetDesc(),
+			Weight: task.GetWeight(),
+			State:  w.taskState(task),
+		}
+		if run, ok := w.runs[task]; ok {
+			summary.Start = run.start
+			summary.End = run.end
+			summary.Duration = durationBetween(run.start, run.end, w.clock.Now())
+			summary.Attempts = run.attempts
+		}
+		if failable, ok := task.(Failable); ok && failable.GetError() != nil {
+			summary.Error = failable.GetError().Error()
+		}
+		if summary.State == Skipped {
+			summary.Reason = w.skipReason(task)
+		}
+		report.Tasks = append(report.Tasks, summary)
+	}
+	return report
+}
+
+// recordStart Records start of a run of task
+func (w *Worker) recordStart(task Runnable) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.runs == nil {
+		w.runs = make(map[Runnable]*taskRun)
+	}
+	run, ok := w.runs[task]
+	if !ok {
+		run = &taskRun{}
+		w.runs[task] = run
+	}
+	run.start = w.clock.Now()
+	run.end = time.Time{}
+	run.attempts++
+}
+
+// recordEnd Records end of the run of task, does nothing if its start was not recorded
+func (w *Worker) recordEnd(task Runnable) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	run, ok := w.runs[task]
+	if !ok {
+		return
+	}
+	run.end = w.clock.Now()
+	w.recordHistory(task, run.end.Sub(run.start))
+}
+
+// durationBetween Returns duration from start to end, up to now if end is not set yet
+func durationBetween(start time.Time, end time.Time, now time.Time) time.Duration {
+	if end.IsZero() {
+		return now.Sub(start)
+	}
+	return end.Sub(start)
+}
+
+// Text Renders report as plain text table
+func (r Report) Text() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Worker '%s' %s after %v\n", r.Worker, StateToString(r.State), formatDuration(r.Duration))
+	if r.Error != "" {
+		fmt.Fprintf(&buf, "Error: %s\n", r.Error)
+	}
+	table := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TASK\tSTATE\tWEIGHT\tDURATION\tATTEMPTS\tERROR")
+	for _, task := range r.Tasks {
+		fmt.Fprintf(table, "%s\t%s\t%v\t%v\t%d\t%s\n", task.Name, StateToString(task.State), task.Weight,
+			formatDuration(task.Duration), task.Attempts, task.Error)
+	}
+	table.Flush()
+	return buf.String()
+}
+
+// Markdown Renders report as Markdown table with a heading
+func (r Report) Markdown() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "### %s\n\n", markdownEscape(r.Worker))
+	fmt.Fprintf(&buf, "**%s** after %v\n\n", StateToString(r.State), formatDuration(r.Duration))
+	if r.Error != "" {
+		fmt.Fprintf(&buf, "Error: %s\n\n", markdownEscape(r.Error))
+	}
+	fmt.Fprintln(&buf, "| Task | Description | State | Weight | Duration | Attempts | Error |")
+	fmt.Fprintln(&buf, "|------|-------------|-------|-------:|---------:|---------:|-------|")
+	for _, task := range r.Tasks {
+		fmt.Fprintf(&buf, "| %s | %s | %s | %v | %v | %d | %s |\n", markdownEscape(task.Name), markdownEscape(task.Desc),
+			StateToString(task.State), task.Weight, formatDuration(task.Duration), task.Attempts, markdownEscape(task.Error))
+	}
+	return buf.String()
+}
+
+// JSON Renders report as indented JSON
+func (r Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// formatDuration Returns duration rounded to milliseconds
+func formatDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
+
+// markdownEscape Escapes characters which would break a Markdown table cell
+func markdownEscape(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
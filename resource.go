@@ -0,0 +1,223 @@
+package gotask
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceManager Manages named resources with limited units which can be shared between tasks of multiple workers
+// A task acquires all its resources at once or none of them, so tasks waiting for resources never hold any and can not
+// deadlock each other. Waiting tasks are served in arrival order per resource, so no task starves.
+type ResourceManager struct {
+	mu        sync.Mutex
+	resources map[string]*resource
+	waiters   []*resourceRequest // waiting requests in arrival order
+}
+
+// resource Capacity and current usage of one named resource
+type resource struct {
+	capacity int
+	used     int
+}
+
+// resourceRequest Request of a task for units of resources
+type resourceRequest struct {
+	worker  string
+	task    string
+	units   map[string]int
+	held    map[string]int // units actually granted per resource, may be clamped to capacity
+	since   time.Time
+	granted bool
+	ready   chan struct{} // closed once request was granted
+}
+
+// ResourceWait Information about a task waiting for resources
+type ResourceWait struct {
+	Worker    string         // name of worker the task belongs to
+	Task      string         // name of waiting task
+	Resources map[string]int // units requested per resource
+	Since     time.Time      // time the task started waiting, taken from the clock of its worker
+}
+
+// NewResourceManager Factory method for creating a new resource manager
+func NewResourceManager() *ResourceManager {
+	manager := ResourceManager{
+		resources: make(map[string]*resource),
+	}
+	return &manager
+}
+
+// SetCapacity Sets amount of units of resource which can be used at the same time
+// Note: Resources which were not set have a capacity of one, a task requiring more units than the capacity gets exclusive access.
+func (m *ResourceManager) SetCapacity(name string, capacity int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if capacity < 1 {
+		capacity = 1
+	}
+	m.get(name).capacity = capacity
+	m.grant()
+}
+
+// GetUsage Returns units of resource currently in use and its capacity
+func (m *ResourceManager) GetUsage(name string) (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := m.get(name)
+	return res.used, res.capacity
+}
+
+// GetWaiting Returns all tasks waiting for resources in arrival order
+func (m *ResourceManager) GetWaiting() []ResourceWait {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	waiting := make([]ResourceWait, 0, len(m.waiters))
+	for _, req := range m.waiters {
+		units := make(map[string]int, len(req.units))
+		for name, amount := range req.units {
+			units[name] = amount
+		}
+		waiting = append(waiting, ResourceWait{Worker: req.worker, Task: req.task, Resources: units, Since: req.since})
+	}
+	return waiting
+}
+
+// get Returns resource by name and creates it with a capacity of one if not existing, caller must hold the lock
+func (m *ResourceManager) get(name string) *resource {
+	res, ok := m.resources[name]
+	if !ok {
+		res = &resource{capacity: 1}
+		m.resources[name] = res
+	}
+	return res
+}
+
+// request Queues request for resource units made at since, which is granted immediately if possible
+func (m *ResourceManager) request(worker string, task string, units map[string]int, since time.Time) *resourceRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	req := &resourceRequest{
+		worker: worker,
+		task:   task,
+		units:  units,
+		since:  since,
+		ready:  make(chan struct{}),
+	}
+	m.waiters = append(m.waiters, req)
+	m.grant()
+	return req
+}
+
+// release Returns units of granted request or withdraws a request still waiting
+func (m *ResourceManager) release(req *resourceRequest) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if req.granted {
+		for name, amount := range req.held {
+			m.get(name).used -= amount
+		}
+		req.granted = false
+	} else {
+		for idx, waiter := range m.waiters {
+			if waiter == req {
+				m.waiters = append(m.waiters[:idx], m.waiters[idx+1:]...)
+				break
+			}
+		}
+	}
+	m.grant()
+}
+
+// grant Grants waiting requests in arrival order, caller must hold the lock
+// A request whose resources are needed by an earlier waiting request is not granted, even if enough units are free.
+func (m *ResourceManager) grant() {
+	blocked := make(map[string]bool)
+	remaining := m.waiters[:0]
+	for _, req := range m.waiters {
+		if m.available(req, blocked) {
+			req.held = make(map[string]int, len(req.units))
+			for name, amount := range req.units {
+				res := m.get(name)
+				req.held[name] = m.clamp(res, amount)
+				res.used += req.held[name]
+			}
+			req.granted = true
+			close(req.ready)
+			continue
+		}
+		for name := range req.units {
+			blocked[name] = true
+		}
+		remaining = append(remaining, req)
+	}
+	for idx := len(remaining); idx < len(m.waiters); idx++ {
+		m.waiters[idx] = nil
+	}
+	m.waiters = remaining
+}
+
+// available Checks if all resources of request are free and not blocked by earlier requests, caller must hold the lock
+func (m *ResourceManager) available(req *resourceRequest, blocked map[string]bool) bool {
+	for name, amount := range req.units {
+		res := m.get(name)
+		if blocked[name] || res.used+m.clamp(res, amount) > res.capacity {
+			return false
+		}
+	}
+	return true
+}
+
+// clamp Limits requested units to capacity of resource, so oversized requests get exclusive access instead of blocking forever
+func (m *ResourceManager) clamp(res *resource, amount int) int {
+	if amount > res.capacity {
+		return res.capacity
+	}
+	return amount
+}
+
+// acquire Waits until all resources required by task are granted while honouring stop and deadline of the scope
+// Returns the granted request which must be released after the task run, nil if the task requires no resources
+func (w *Worker) acquire(task Runnable, scope runScope) (*resourceRequest, error) {
+	w.mu.Lock()
+	manager := w.resources
+	clock := w.clock
+	w.mu.Unlock()
+
+	user, ok := task.(ResourceUser)
+	if manager == nil || !ok || len(user.GetResources()) == 0 {
+		return nil, nil
+	}
+
+	lease := manager.request(w.name, task.GetName(), user.GetResources(), clock.Now())
+	select {
+	case <-lease.ready:
+		return lease, nil
+	default:
+	}
+
+	w.setWaitingFor(task, "resource: "+describeResources(user.GetResources()))
+	defer w.setWaitingFor(task, "")
+
+	select {
+	case <-lease.ready:
+		return lease, nil
+	case <-w.quit:
+		manager.release(lease)
+		return nil, ErrWorkerCanceledByUser
+	case <-scope.after():
+		manager.release(lease)
+		return nil, scope.deadlineErr
+	}
+}
+
+// describeResources Returns sorted, comma separated resource names for status information
+func describeResources(units map[string]int) string {
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
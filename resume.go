@@ -0,0 +1,90 @@
+package gotask
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrWorkerNothingToRerun error = errors.New("worker has no tasks to rerun")
+)
+
+// Resume Continues a stopped, timed out or failed run by running all tasks which did not finish successfully again
+// Finished tasks keep their state and progress and are not run again, unless their compensation was run after the
+// last run. Tasks added meanwhile are run as well.
+// timeout Timeout of the resumed run, see Run
+func (w *Worker) Resume(timeout time.Duration) error {
+	return w.rerun(timeout, func(task Runnable) bool {
+		return task.GetState() != Finished
+	})
+}
+
+// RerunFailed Runs the failed tasks of the last run again, all other tasks keep their state and are not run
+// timeout Timeout of the rerun, see Run
+func (w *Worker) RerunFailed(timeout time.Duration) error {
+	return w.rerun(timeout, func(task Runnable) bool {
+		return task.GetState() == Failed
+	})
+}
+
+// rerun Resets all tasks selected by rerun and starts a run of these tasks, the others are kept as they are
+// Returns ErrWorkerNothingToRerun if no task is selected
+func (w *Worker) rerun(timeout time.Duration, rerun func(task Runnable) bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.busy() {
+		return ErrWorkerRunning
+	}
+	switch w.state {
+	case Waiting:
+		return ErrWorkerNotStarted
+	}
+
+	// tasks whose work was undone by their compensation have to be run again
+	compensated := make(map[Runnable]struct{})
+	if len(w.compensations) > 0 {
+		for _, task := range w.finishedTasks {
+			if compensable, ok := task.(Compensable); ok && compensable.HasCompensation() {
+				compensated[task] = struct{}{}
+			}
+		}
+	}
+
+	var selected []Runnable
+	kept := make(map[Runnable]struct{})
+	for _, task := range w.taskQueue {
+		if _, skipped := w.skipped[task]; skipped {
+			continue
+		}
+		if _, ok := compensated[task]; ok || rerun(task) {
+			selected = append(selected, task)
+		} else {
+			kept[task] = struct{}{}
+		}
+	}
+	if len(selected) == 0 {
+		return ErrWorkerNothingToRerun
+	}
+	for _, task := range selected {
+		if err := task.Reset(); err != nil {
+			return err
+		}
+	}
+
+	var finished []Runnable
+	for _, task := range w.finishedTasks {
+		if _, ok := kept[task]; ok {
+			finished = append(finished, task)
+		}
+	}
+	for _, stage := range w.stages {
+		stage.state = Waiting
+	}
+	w.err = nil
+	w.finishedTasks = finished
+	w.compensations = nil
+	w.kept = kept
+	w.currStage = nil
+	w.start(timeout)
+	return nil
+}
@@ -0,0 +1,137 @@
+package gotask
+
+import (
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// BrokerRequest Arguments of a broker call over net/rpc
+type BrokerRequest struct {
+	Agent  string
+	Wait   time.Duration
+	Report TaskReport
+}
+
+// BrokerReply Reply of a broker call over net/rpc
+type BrokerReply struct {
+	Spec *TaskSpec // polled task, nil if none arrived
+}
+
+// brokerService Serves broker calls of one connection and remembers the agents registered over it
+type brokerService struct {
+	broker *MemoryBroker
+	mu     sync.Mutex
+	agents map[string]bool
+}
+
+// Register Registers agent and binds it to the connection
+func (s *brokerService) Register(req BrokerRequest, reply *BrokerReply) error {
+	s.mu.Lock()
+	s.agents[req.Agent] = true
+	s.mu.Unlock()
+	return s.broker.Register(req.Agent)
+}
+
+// Unregister Removes agent
+func (s *brokerService) Unregister(req BrokerRequest, reply *BrokerReply) error {
+	s.mu.Lock()
+	delete(s.agents, req.Agent)
+	s.mu.Unlock()
+	return s.broker.Unregister(req.Agent)
+}
+
+// Poll Returns next task for agent
+func (s *brokerService) Poll(req BrokerRequest, reply *BrokerReply) error {
+	spec, err := s.broker.Poll(req.Agent, req.Wait)
+	reply.Spec = spec
+	return err
+}
+
+// Report Records progress or result of task
+func (s *brokerService) Report(req BrokerRequest, reply *BrokerReply) error {
+	return s.broker.Report(req.Agent, req.Report)
+}
+
+// Serve Accepts agent connections on listener and serves broker calls over net/rpc until the listener is closed
+// Agents registered over a connection are unregistered once the connection is lost, so their tasks are re-queued at once.
+func (m *MemoryBroker) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go m.serveConn(conn)
+	}
+}
+
+// serveConn Serves broker calls of one connection and drops its agents once the connection is closed
+func (m *MemoryBroker) serveConn(conn net.Conn) {
+	service := &brokerService{broker: m, agents: make(map[string]bool)}
+	server := rpc.NewServer()
+	_ = server.RegisterName("Broker", service)
+	server.ServeConn(conn)
+
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	for agent := range service.agents {
+		_ = m.Unregister(agent)
+	}
+}
+
+// RPCBroker Broker client talking to a MemoryBroker served over net/rpc, used by agents on other machines
+type RPCBroker struct {
+	client *rpc.Client
+}
+
+// DialBroker Connects to broker served at address over TCP
+func DialBroker(address string) (*RPCBroker, error) {
+	client, err := rpc.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &RPCBroker{client: client}, nil
+}
+
+// Close Closes connection to broker, the broker drops all agents registered over it
+func (b *RPCBroker) Close() error {
+	return b.client.Close()
+}
+
+// Register Registers agent at broker
+func (b *RPCBroker) Register(agent string) error {
+	return b.call("Broker.Register", BrokerRequest{Agent: agent}, &BrokerReply{})
+}
+
+// Unregister Removes agent from broker
+func (b *RPCBroker) Unregister(agent string) error {
+	return b.call("Broker.Unregister", BrokerRequest{Agent: agent}, &BrokerReply{})
+}
+
+// Poll Returns next task for agent, nil if none arrived within wait
+func (b *RPCBroker) Poll(agent string, wait time.Duration) (*TaskSpec, error) {
+	reply := BrokerReply{}
+	if err := b.call("Broker.Poll", BrokerRequest{Agent: agent, Wait: wait}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Spec, nil
+}
+
+// Report Reports progress or result of task assigned to agent
+func (b *RPCBroker) Report(agent string, report TaskReport) error {
+	return b.call("Broker.Report", BrokerRequest{Agent: agent, Report: report}, &BrokerReply{})
+}
+
+// call Calls broker method and maps errors sent as text back to the broker errors
+func (b *RPCBroker) call(method string, req BrokerRequest, reply *BrokerReply) error {
+	err := b.client.Call(method, req, reply)
+	if serverErr, ok := err.(rpc.ServerError); ok {
+		for _, known := range []error{ErrBrokerAgentUnknown, ErrBrokerTaskUnknown} {
+			if string(serverErr) == known.Error() {
+				return known
+			}
+		}
+	}
+	return err
+}
@@ -11,3 +11,24 @@ type Runnable interface {
 	GetWorkLoad() int      // returns task workload (progress times weight)
 	Reset() error          // Resets task to start state
 }
+
+// Tagged Optional interface for tasks carrying tags, e.g. used for per tag rate limiting
+type Tagged interface {
+	GetTags() []string // returns tags of task
+}
+
+// ResourceUser Optional interface for tasks requiring units of named resources of a ResourceManager
+type ResourceUser interface {
+	GetResources() map[string]int // returns units required per resource name
+}
+
+// Failable Optional interface for tasks which can fail, a failed task stops the worker
+type Failable interface {
+	GetError() error // returns error of last run, nil if successful
+}
+
+// Compensable Optional interface for tasks which can undo their work if a later task of the worker fails
+type Compensable interface {
+	HasCompensation() bool // returns true if task has a compensation
+	Compensate() error     // undoes work of finished task
+}
@@ -0,0 +1,369 @@
+package gotask
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSchedulerRunning    error = errors.New("scheduler already running")
+	ErrSchedulerNotRunning error = errors.New("scheduler is not running")
+)
+
+// OverlapPolicy Defines what a scheduler does if a run is due while the previous run is still in progress
+type OverlapPolicy uint8
+
+const (
+	OverlapSkip   OverlapPolicy = iota // due run is skipped
+	OverlapQueue  OverlapPolicy = iota // due run is started once the previous run finished
+	OverlapCancel OverlapPolicy = iota // previous run is stopped and the due run is started afterwards
+)
+
+// Scheduler Runs workers repeatedly on a cron or interval schedule
+type Scheduler struct {
+	mu       sync.Mutex
+	name     string
+	schedule Schedule
+	factory  func() *Worker // creates or resets the worker for every run
+	timeout  time.Duration  // timeout passed to every worker run
+	policy   OverlapPolicy
+	jitter   time.Duration // maximum random delay added to every activation time
+	catchUp  int           // maximum amount of missed runs executed afterwards, zero disables catch-up
+	location *time.Location
+	clock    Clock
+	rand     *rand.Rand
+	lastRun  time.Time // activation time of last run, next activation is calculated from this if set
+	nextRun  time.Time
+	current  *Worker // worker of run in progress, nil if no run in progress
+	starting bool    // set while the factory of the next run is called
+	pending  int     // amount of runs queued behind the run in progress
+	runs     int     // amount of started runs
+	skipped  int     // amount of runs skipped due to overlap or missed activation
+	lastErr  error   // result of last finished run
+	running  bool
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewScheduler Factory method for creating a scheduler which creates a fresh worker for every run using the factory
+// The factory is called without holding the scheduler lock, so it may use the getters of the scheduler.
+func NewScheduler(name string, schedule Schedule, factory func() *Worker) *Scheduler {
+	scheduler := Scheduler{
+		name:     name,
+		schedule: schedule,
+		factory:  factory,
+		policy:   OverlapSkip,
+		location: time.Local,
+		clock:    SystemClock,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	return &scheduler
+}
+
+// NewWorkerScheduler Factory method for creating a scheduler which resets and reruns the same worker for every run
+// Note: Runs of one scheduler never overlap, so the same worker can be reused independent of the overlap policy
+func NewWorkerScheduler(name string, schedule Schedule, worker *Worker) *Scheduler {
+	return NewScheduler(name, schedule, func() *Worker {
+		_ = worker.Reset()
+		return worker
+	})
+}
+
+// SetTimeout Sets timeout passed to every worker run, a value of zero or below sets no timeout
+func (s *Scheduler) SetTimeout(timeout time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.timeout = timeout
+	return nil
+}
+
+// SetOverlapPolicy Sets behavior if a run is due while the previous run is still in progress, default is OverlapSkip
+func (s *Scheduler) SetOverlapPolicy(policy OverlapPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.policy = policy
+	return nil
+}
+
+// SetJitter Sets maximum random delay added to every activation time to spread load of many schedulers
+func (s *Scheduler) SetJitter(jitter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.jitter = jitter
+	return nil
+}
+
+// SetCatchUp Sets maximum amount of missed activations which are run afterwards, e.g. after the system was suspended
+// Missed runs are executed one after another. A value of zero disables catch-up and only runs the latest missed activation.
+func (s *Scheduler) SetCatchUp(maxRuns int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.catchUp = maxRuns
+	return nil
+}
+
+// SetLocation Sets time zone cron expressions are evaluated in, default is the local time zone
+func (s *Scheduler) SetLocation(location *time.Location) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.location = location
+	return nil
+}
+
+// SetClock Sets clock used for all time calculations, default is SystemClock
+func (s *Scheduler) SetClock(clock Clock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.clock = clock
+	return nil
+}
+
+// SetLastRun Sets activation time of the last run, e.g. persisted before a restart
+// The next activation is calculated from this time, so activations missed in between are caught up.
+func (s *Scheduler) SetLastRun(lastRun time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+	s.lastRun = lastRun
+	return nil
+}
+
+// Start Starts scheduling worker runs
+func (s *Scheduler) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return ErrSchedulerRunning
+	}
+
+	from := s.clock.Now()
+	if !s.lastRun.IsZero() {
+		from = s.lastRun
+	}
+	s.nextRun = s.schedule.Next(from.In(s.location))
+	s.running = true
+	s.quit = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.loop()
+	return nil
+}
+
+// Stop Stops scheduling, stops the run in progress and drops all queued runs. Waits until the run in progress is stopped.
+func (s *Scheduler) Stop() error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return ErrSchedulerNotRunning
+	}
+	s.running = false
+	s.pending = 0
+	close(s.quit)
+	current := s.current
+	s.mu.Unlock()
+
+	if current != nil {
+		_ = current.Stop()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+// GetName Returns scheduler name
+func (s *Scheduler) GetName() string {
+	return s.name
+}
+
+// IsRunning Convienince function to check if scheduler is started
+func (s *Scheduler) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// GetNextRun Returns next activation time without jitter, zero time if scheduler is not running or schedule is exhausted
+func (s *Scheduler) GetNextRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return time.Time{}
+	}
+	return s.nextRun
+}
+
+// GetLastRun Returns activation time of the last triggered run
+func (s *Scheduler) GetLastRun() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastRun
+}
+
+// GetCurrentWorker Returns worker of run in progress, nil if no run is in progress
+func (s *Scheduler) GetCurrentWorker() *Worker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current
+}
+
+// GetRunCount Returns amount of started runs
+func (s *Scheduler) GetRunCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs
+}
+
+// GetSkippedCount Returns amount of runs skipped due to overlap policy or missed activations
+func (s *Scheduler) GetSkippedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.skipped
+}
+
+// GetLastError Returns result of the last finished run
+func (s *Scheduler) GetLastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// loop Waits for activation times and triggers runs until scheduler is stopped
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		next := s.nextRun
+		if next.IsZero() {
+			s.mu.Unlock()
+			return
+		}
+		activation := next
+		if s.jitter > 0 {
+			activation = activation.Add(time.Duration(s.rand.Int63n(int64(s.jitter))))
+		}
+		wait := activation.Sub(s.clock.Now())
+		clock := s.clock
+		s.mu.Unlock()
+
+		if wait > 0 {
+			select {
+			case <-s.quit:
+				return
+			case <-clock.After(wait):
+			}
+		}
+
+		s.mu.Lock()
+		if !s.running {
+			s.mu.Unlock()
+			return
+		}
+		// collect all activations missed while waiting, e.g. due to a suspended system or a last run far in the past
+		now := clock.Now().In(s.location)
+		due := 1
+		last := next
+		for {
+			following := s.schedule.Next(last)
+			if following.IsZero() || following.After(now) {
+				s.nextRun = following
+				break
+			}
+			last = following
+			due++
+		}
+		s.lastRun = last
+
+		runs := 1
+		if s.catchUp > 0 {
+			runs = due
+			if runs > s.catchUp+1 {
+				runs = s.catchUp + 1
+			}
+		}
+		s.skipped += due - runs
+		s.trigger(runs)
+		s.mu.Unlock()
+	}
+}
+
+// trigger Starts runs according to overlap policy, caller must hold the scheduler lock
+// A run whose worker is still being created counts as in progress, but is only canceled by the following runs.
+func (s *Scheduler) trigger(runs int) {
+	if s.current != nil || s.starting {
+		switch s.policy {
+		case OverlapSkip:
+			s.skipped += runs
+		case OverlapQueue:
+			s.pending += runs
+		case OverlapCancel:
+			// the finishing run starts the pending runs, the queued runs of the canceled run are dropped
+			s.skipped += s.pending
+			s.pending = runs
+			if s.current != nil {
+				go s.current.Stop()
+			}
+		}
+		return
+	}
+	s.pending = runs - 1
+	s.start()
+}
+
+// start Creates worker and runs it, caller must hold the scheduler lock
+// The lock is released while the factory is called. The worker is run under the lock, so a Stop seeing the current
+// worker always finds it running, and a worker created after Stop is not run at all.
+func (s *Scheduler) start() {
+	s.starting = true
+	s.runs++
+	s.mu.Unlock()
+	worker := s.factory()
+	s.mu.Lock()
+	s.starting = false
+	if !s.running {
+		s.runs--
+		return
+	}
+	s.current = worker
+	err := worker.Run(s.timeout)
+	s.wg.Add(1)
+	go s.execute(worker, err)
+}
+
+// execute Waits until worker started with result err finished and starts next queued run afterwards
+func (s *Scheduler) execute(worker *Worker, err error) {
+	defer s.wg.Done()
+
+	if err == nil && worker.GetState() != Waiting { // a worker without tasks does not start at all
+		err = worker.Wait()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = nil
+	s.lastErr = err
+	if s.running && s.pending > 0 {
+		s.pending--
+		s.start()
+	}
+}
@@ -0,0 +1,214 @@
+package gotask
+
+import (
+	"path"
+	"regexp"
+)
+
+// Labeled Optional interface for tasks carrying key/value labels, e.g. used for selecting tasks
+type Labeled interface {
+	GetLabels() map[string]string // returns labels of task
+}
+
+// Selection Filter selecting the tasks of a worker which are run, all other tasks are skipped
+// A task is selected if it carries none of the excluded tags, at least one of the included tags, matches at least one
+// of the name globs or patterns and carries all labels. Criteria not set do not restrict the selection.
+type Selection struct {
+	includeTags []string
+	excludeTags []string
+	globs       []string
+	patterns    []*regexp.Regexp
+	labels      map[string]string
+}
+
+// NewSelection Factory method for creating a new selection, which selects all tasks until criteria are added
+func NewSelection() *Selection {
+	selection := Selection{labels: make(map[string]string)}
+	return &selection
+}
+
+// IncludeTags Selects only tasks carrying at least one of tags, returns selection for chaining
+func (s *Selection) IncludeTags(tags ...string) *Selection {
+	s.includeTags = append(s.includeTags, tags...)
+	return s
+}
+
+// ExcludeTags Skips tasks carrying any of tags, even if they are selected otherwise, returns selection for chaining
+func (s *Selection) ExcludeTags(tags ...string) *Selection {
+	s.excludeTags = append(s.excludeTags, tags...)
+	return s
+}
+
+// MatchNames Selects only tasks whose name matches at least one of globs or patterns, returns selection for chaining
+// Globs use the syntax of path.Match, e.g. "build-*"
+func (s *Selection) MatchNames(globs ...string) *Selection {
+	s.globs = append(s.globs, globs...)
+	return s
+}
+
+// MatchPattern Selects only tasks whose name matches pattern or at least one of the globs, returns selection for chaining
+func (s *Selection) MatchPattern(pattern *regexp.Regexp) *Selection {
+	s.patterns = append(s.patterns, pattern)
+	return s
+}
+
+// MatchLabel Selects only tasks carrying label key with value, returns selection for chaining
+func (s *Selection) MatchLabel(key string, value string) *Selection {
+	s.labels[key] = value
+	return s
+}
+
+// Matches Checks if task is selected
+func (s *Selection) Matches(task Runnable) bool {
+	var tags []string
+	if tagged, ok := task.(Tagged); ok {
+		tags = tagged.GetTags()
+	}
+	for _, tag := range s.excludeTags {
+		if containsTag(tags, tag) {
+			return false
+		}
+	}
+	if len(s.includeTags) > 0 {
+		included := false
+		for _, tag := range s.includeTags {
+			included = included || containsTag(tags, tag)
+		}
+		if !included {
+			return false
+		}
+	}
+
+	if len(s.globs) > 0 || len(s.patterns) > 0 {
+		matched := false
+		for _, glob := range s.globs {
+			ok, _ := path.Match(glob, task.GetName())
+			matched = matched || ok
+		}
+		for _, pattern := range s.patterns {
+			matched = matched || pattern.MatchString(task.GetName())
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(s.labels) > 0 {
+		labeled, ok := task.(Labeled)
+		if !ok {
+			return false
+		}
+		labels := labeled.GetLabels()
+		for key, value := range s.labels {
+			if own, ok := labels[key]; !ok || own != value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// validate Checks if all globs are valid
+func (s *Selection) validate() error {
+	for _, glob := range s.globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containsTag Checks if tags contain tag
+func containsTag(tags []string, tag string) bool {
+	for _, own := range tags {
+		if own == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSelection Sets selection of tasks which are run, all other tasks are skipped. Set nil to run all tasks.
+// The selection is evaluated for all tasks at Run, tasks added while the worker is running, e.g. subtasks, are always
+// run. Workloads and progress only cover selected tasks. Returns path.ErrBadPattern for invalid name globs.
+func (w *Worker) SetSelection(selection *Selection) error {
+	if selection != nil {
+		if err := selection.validate(); err != nil {
+			return err
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.selection = selection
+	w.skipped = nil
+	w.selectTasks(w.taskQueue)
+	w.recount(false)
+	return nil
+}
+
+// IsSelected Checks if task is selected by the selection of the worker, i.e. if it is run and not skipped
+func (w *Worker) IsSelected(task Runnable) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, skipped := w.skipped[task]
+	return !skipped
+}
+
+// selectTasks Marks tasks not matching the selection as skipped, caller must hold the worker lock
+func (w *Worker) selectTasks(tasks []Runnable) {
+	if w.selection == nil {
+		return
+	}
+	for _, task := range tasks {
+		if w.selection.Matches(task) {
+			delete(w.skipped, task)
+			continue
+		}
+		if w.skipped == nil {
+			w.skipped = make(map[Runnable]struct{})
+		}
+		w.skipped[task] = struct{}{}
+	}
+}
+
+// reasons reported for skipped tasks
+const (
+	skipNotSelected = "task was not selected"
+	skipUnfit       = "optional task could not finish in time"
+	skipBypassed    = "task was not run by middleware"
+)
+
+// skipReason Returns why task was skipped, empty if it was not skipped, caller must hold the worker lock
+func (w *Worker) skipReason(task Runnable) string {
+	if _, skipped := w.skipped[task]; skipped {
+		return skipNotSelected
+	}
+	if _, unfit := w.unfit[task]; unfit {
+		return skipUnfit
+	}
+	if _, bypassed := w.bypassed[task]; bypassed {
+		return skipBypassed
+	}
+	return ""
+}
+
+// isSkipped Checks if task is skipped by the selection, as optional task not fitting the timeout or by middleware,
+// caller must hold the worker lock
+func (w *Worker) isSkipped(task Runnable) bool {
+	_, skipped := w.skipped[task]
+	_, unfit := w.unfit[task]
+	_, bypassed := w.bypassed[task]
+	return skipped || unfit || bypassed
+}
+
+// taskState Returns state of task, which is Skipped for started workers if the task was skipped
+// Caller must hold the worker lock
+func (w *Worker) taskState(task Runnable) State {
+	if w.state != Waiting && w.isSkipped(task) {
+		return Skipped
+	}
+	return task.GetState()
+}
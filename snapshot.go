@@ -0,0 +1,140 @@
+package gotask
+
+import "time"
+
+// WorkerSnapshot Serializable status of a worker, e.g. to ship it to a frontend or persist it
+// A snapshot is a copy taken at one point in time, changing it does not affect the worker.
+type WorkerSnapshot struct {
+	Time              time.Time       `json:"time"` // time the snapshot was taken
+	Name              string          `json:"name"`
+	State             State           `json:"state"`
+	Progress          Progress        `json:"progress"`
+	Error             string          `json:"error,omitempty"` // error the last run ended with
+	Start             time.Time       `json:"start"`           // zero if the worker was not started
+	End               time.Time       `json:"end"`             // zero while the worker is running or was not started
+	Duration          time.Duration   `json:"duration"`        // duration of the run in nanoseconds, up to Time while running
+	Timeout           time.Time       `json:"timeout"`         // time the timeout will be reached, zero if no timeout set
+	RemainingTime     time.Duration   `json:"remainingTime"`   // time until timeout, -1 if no timeout set and zero if not running
+	CurrentTask       string          `json:"currentTask"`     // name of most recently started task, empty if not running
+	CurrentTaskDesc   string          `json:"currentTaskDesc"`
+	CurrentStage      string          `json:"currentStage"` // empty if not running
+	WaitingFor        string          `json:"waitingFor"`   // what the current task waits for before it is started
+	TotalWorkLoad     float64         `json:"totalWorkLoad"`
+	RemainingWorkLoad float64         `json:"remainingWorkLoad"`
+	Stages            []StageSnapshot `json:"stages"`
+	Tasks             []TaskSnapshot  `json:"tasks"`
+}
+
+// StageSnapshot Serializable status of one stage of a worker
+type StageSnapshot struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	Tasks int    `json:"tasks"` // amount of tasks of stage
+}
+
+// TaskSnapshot Serializable status of one task of a worker
+type TaskSnapshot struct {
+	Name       string        `json:"name"`
+	Desc       string        `json:"desc"`
+	Stage      string        `json:"stage"`
+	Weight     Weight        `json:"weight"`
+	State      State         `json:"state"`
+	Progress   Progress      `json:"progress"`
+	Error      string        `json:"error,omitempty"` // error of failed tasks implementing Failable
+	Start      time.Time     `json:"start"`           // zero if the task was not started
+	End        time.Time     `json:"end"`             // zero if the task was not started or is running
+	Duration   time.Duration `json:"duration"`
+	Attempts   int           `json:"attempts"`             // amount of times the task was started since the worker was reset
+	WaitingFor string        `json:"waitingFor,omitempty"` // what the task waits for before it is started
+}
+
+// Snapshot Returns status of worker, its stages and all its tasks in queue order
+// All values are captured under a single lock, so the run loop can not start a task or advance to the next stage in
+// between and the values are consistent with each other. Progress and workloads are computed from the captured tasks.
+func (w *Worker) Snapshot() WorkerSnapshot {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+	snapshot := WorkerSnapshot{
+		Time:       now,
+		Name:       w.name,
+		State:      w.state,
+		Progress:   w.progress,
+		WaitingFor: w.waitingFor[w.currSubTask],
+		Stages:     make([]StageSnapshot, 0, len(w.stages)),
+		Tasks:      make([]TaskSnapshot, 0, len(w.taskQueue)),
+	}
+	if w.err != nil {
+		snapshot.Error = w.err.Error()
+	}
+	if w.state != Waiting {
+		snapshot.Start = w.startTime
+		snapshot.End = w.endTime
+		end := w.endTime
+		if end.IsZero() {
+			end = now
+		}
+		snapshot.Duration = end.Sub(w.startTime)
+	}
+	if w.timeoutSet {
+		snapshot.Timeout = w.timeoutTime
+	}
+	if w.state == Running {
+		snapshot.RemainingTime = -1
+		if w.timeoutSet {
+			snapshot.RemainingTime = w.timeoutTime.Sub(now)
+		}
+		if w.currSubTask != nil {
+			snapshot.CurrentTask = w.currSubTask.GetName()
+			snapshot.CurrentTaskDesc = w.currSubTask.GetDesc()
+		}
+		if w.currStage != nil {
+			snapshot.CurrentStage = w.currStage.name
+		}
+	}
+
+	stageOf := make(map[Runnable]string, len(w.taskQueue))
+	for _, stage := range w.stages {
+		snapshot.Stages = append(snapshot.Stages, StageSnapshot{Name: stage.name, State: stage.state, Tasks: len(stage.tasks)})
+		for _, task := range stage.tasks {
+			stageOf[task] = stage.name
+		}
+	}
+
+	workDone := 0.0
+	for _, task := range w.taskQueue {
+		taskSnapshot := TaskSnapshot{
+			Name:       task.GetName(),
+			Desc:       task.GetDesc(),
+			Stage:      stageOf[task],
+			Weight:     task.GetWeight(),
+			State:      w.taskState(task),
+			Progress:   task.GetProgress(),
+			WaitingFor: w.waitingFor[task],
+		}
+		if failable, ok := task.(Failable); ok && failable.GetError() != nil {
+			taskSnapshot.Error = failable.GetError().Error()
+		}
+		if run, ok := w.runs[task]; ok {
+			taskSnapshot.Start = run.start
+			taskSnapshot.End = run.end
+			end := run.end
+			if end.IsZero() {
+				end = now
+			}
+			taskSnapshot.Duration = end.Sub(run.start)
+			taskSnapshot.Attempts = run.attempts
+		}
+		snapshot.Tasks = append(snapshot.Tasks, taskSnapshot)
+		if w.isSkipped(task) {
+			continue
+		}
+		snapshot.TotalWorkLoad += float64(taskSnapshot.Weight)
+		workDone += float64(taskSnapshot.Progress) / float64(MaxProgress) * float64(taskSnapshot.Weight)
+	}
+	snapshot.RemainingWorkLoad = snapshot.TotalWorkLoad - workDone
+	if w.state == Running && snapshot.TotalWorkLoad > 0 {
+		snapshot.Progress = Progress(workDone/snapshot.TotalWorkLoad) * 100 // multiply by 100 for percent
+	}
+	return snapshot
+}
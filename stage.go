@@ -0,0 +1,367 @@
+package gotask
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrStageTimeoutReached error = errors.New("stage reached timeout limit")
+	ErrStageFinished       error = errors.New("stage already completed in running worker, can not add tasks")
+)
+
+// ErrorPolicy Defines how a stage reacts to failed tasks
+type ErrorPolicy uint8
+
+const (
+	StopOnError     ErrorPolicy = iota // no more tasks of the stage are started after a task failed and the worker fails
+	ContinueOnError ErrorPolicy = iota // all tasks of the stage are run, the worker fails after the stage if any task failed
+	IgnoreErrors    ErrorPolicy = iota // failed tasks are ignored and the worker continues with the next stage
+)
+
+// StageError Error of a worker run which ended due to multiple failed tasks of one stage
+type StageError struct {
+	Stage  string  // name of stage
+	Errors []error // errors of all failed tasks, every error is a TaskError
+}
+
+// Error Returns error message containing stage name and amount of failed tasks
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%v: %d tasks of stage '%s' failed, first: %v", ErrWorkerTaskFailed, len(e.Errors), e.Stage, e.Errors[0])
+}
+
+// Unwrap Returns error of first failed task
+func (e *StageError) Unwrap() error {
+	return e.Errors[0]
+}
+
+// Stage Named group of tasks inside a worker, a stage starts only after the previous stage completed
+type Stage struct {
+	worker      *Worker
+	name        string
+	timeout     time.Duration // timeout of stage, zero if stage has no own timeout
+	concurrency int           // maximum amount of tasks of the stage running at the same time
+	policy      ErrorPolicy
+	state       State
+	tasks       []Runnable
+}
+
+// runScope Deadline and stage tasks of a worker are run in
+type runScope struct {
+	stage       *Stage
+	deadline    time.Time // zero if neither worker nor stage have a timeout
+	deadlineErr error     // error returned once deadline is reached
+	signal      *cancelSignal
+	clock       Clock
+}
+
+// cancelSignal Broadcasts to running tasks of a stage that the worker was stopped or timed out
+type cancelSignal struct {
+	once sync.Once
+	done chan struct{}
+	err  error // reason of cancellation, only valid once done is closed
+}
+
+// cancel Closes done channel with reason, only the first call has an effect
+func (c *cancelSignal) cancel(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.done)
+	})
+}
+
+// AddStage Appends a new stage to the worker, all tasks added to the worker afterwards belong to this stage
+// Note: A worker without stages runs all its tasks in one unnamed stage
+func (w *Worker) AddStage(name string) *Stage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stage := &Stage{worker: w, name: name, concurrency: 1, state: Waiting}
+	w.stages = append(w.stages, stage)
+	return stage
+}
+
+// GetStages Returns copy of all stages as slice
+func (w *Worker) GetStages() []*Stage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stages := make([]*Stage, len(w.stages))
+	copy(stages, w.stages)
+	return stages
+}
+
+// GetCurrentStageName Returns name of presently running stage
+func (w *Worker) GetCurrentStageName() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state != Running || w.currStage == nil {
+		return "", ErrWorkerNotRunning
+	}
+	return w.currStage.name, nil
+}
+
+// lastStage Returns last stage of worker and creates an unnamed stage if there is none, caller must hold the worker lock
+func (w *Worker) lastStage() *Stage {
+	if len(w.stages) == 0 {
+		w.stages = append(w.stages, &Stage{worker: w, concurrency: 1, state: Waiting})
+	}
+	return w.stages[len(w.stages)-1]
+}
+
+// SetTimeout Sets timeout of stage, after which no more of its tasks are started and the worker ends with TimeoutReached
+// Returns stage for chaining
+func (s *Stage) SetTimeout(timeout time.Duration) *Stage {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	s.timeout = timeout
+	return s
+}
+
+// SetConcurrency Sets maximum amount of tasks of stage running at the same time, values below one are set to one
+// Returns stage for chaining
+func (s *Stage) SetConcurrency(concurrency int) *Stage {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	s.concurrency = concurrency
+	return s
+}
+
+// SetErrorPolicy Sets how stage reacts to failed tasks, default is StopOnError. Returns stage for chaining.
+func (s *Stage) SetErrorPolicy(policy ErrorPolicy) *Stage {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	s.policy = policy
+	return s
+}
+
+// AddTask Adds new task to stage, tasks can also be added while the worker is running
+func (s *Stage) AddTask(task Runnable) error {
+	return s.AddTasks([]Runnable{task})
+}
+
+// AddTasks Adds multiple new tasks to stage
+// Returns ErrStageFinished if the worker is running and the stage already completed, as the tasks would not be run
+func (s *Stage) AddTasks(tasks []Runnable) error {
+	w := s.worker
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running && s.completed() {
+		return ErrStageFinished
+	}
+
+	// the task queue holds tasks of all stages in stage order, so new tasks are inserted behind the tasks of this stage
+	pos := 0
+	for _, stage := range w.stages {
+		pos += len(stage.tasks)
+		if stage == s {
+			break
+		}
+	}
+	if w.state != Running {
+		w.selectTasks(tasks)
+	}
+	for _, task := range tasks {
+		if !w.isSkipped(task) {
+			w.book.add(task)
+		}
+	}
+	queue := make([]Runnable, 0, len(w.taskQueue)+len(tasks))
+	queue = append(queue, w.taskQueue[:pos]...)
+	queue = append(queue, tasks...)
+	w.taskQueue = append(queue, w.taskQueue[pos:]...)
+	s.tasks = append(s.tasks, tasks...)
+	return nil
+}
+
+// completed Checks if stage completed its run, caller must hold the worker lock
+func (s *Stage) completed() bool {
+	return s.state != Waiting && s.state != Running
+}
+
+// GetName Returns stage name
+func (s *Stage) GetName() string {
+	return s.name
+}
+
+// GetState Returns stage state
+func (s *Stage) GetState() State {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	return s.state
+}
+
+// GetSubtasks Returns copy of all tasks of stage as slice
+func (s *Stage) GetSubtasks() []Runnable {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	tasks := make([]Runnable, len(s.tasks))
+	copy(tasks, s.tasks)
+	return tasks
+}
+
+// GetProgress Returns progress of stage in percent from 0 to 100
+func (s *Stage) GetProgress() Progress {
+	s.worker.mu.Lock()
+	defer s.worker.mu.Unlock()
+	workTotal := 0.0
+	workDone := 0.0
+	for _, task := range s.tasks {
+		if s.worker.isSkipped(task) {
+			continue
+		}
+		workTotal += float64(task.GetWeight())
+		workDone += float64(task.GetProgress()) / float64(MaxProgress) * float64(task.GetWeight())
+	}
+	if workTotal == 0 {
+		if s.state == Finished {
+			return MaxProgress
+		}
+		return MinProgress
+	}
+	return Progress(workDone / workTotal * float64(MaxProgress))
+}
+
+// newScope Creates scope for running stage, whose deadline is the earlier one of worker and stage timeout
+func (w *Worker) newScope(stage *Stage) runScope {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	scope := runScope{stage: stage, signal: &cancelSignal{done: make(chan struct{})}, clock: w.clock}
+	if w.timeoutSet {
+		scope.deadline = w.timeoutTime
+		scope.deadlineErr = ErrWorkerTimeoutReached
+	}
+	if stage.timeout > 0 {
+		stageDeadline := w.clock.Now().Add(stage.timeout)
+		if scope.deadline.IsZero() || stageDeadline.Before(scope.deadline) {
+			scope.deadline = stageDeadline
+			scope.deadlineErr = fmt.Errorf("%w: stage '%s'", ErrStageTimeoutReached, stage.name)
+		}
+	}
+	return scope
+}
+
+// expired Checks if deadline of scope is reached
+func (sc runScope) expired() bool {
+	return !sc.deadline.IsZero() && !sc.clock.Now().Before(sc.deadline)
+}
+
+// after Returns channel receiving once the deadline of scope is reached, nil if scope has no deadline
+func (sc runScope) after() <-chan time.Time {
+	if sc.deadline.IsZero() {
+		return nil
+	}
+	return sc.clock.After(sc.deadline.Sub(sc.clock.Now()))
+}
+
+// runStage Runs all tasks of stage with its concurrency and error policy
+// The task list is indexed freshly as running tasks may add tasks to their stage. A stage only completes once all its
+// tasks completed, so the next stage never overlaps.
+func (w *Worker) runStage(stage *Stage) error {
+	scope := w.newScope(stage)
+	w.mu.Lock()
+	w.currStage = stage
+	stage.state = Running
+	concurrency, policy := stage.concurrency, stage.policy
+	w.mu.Unlock()
+
+	done := make(chan error, concurrency)
+	deadline := scope.after()
+	quit := w.quit
+	running := 0
+	var abortErr error
+	var failures []error
+	abort := func(err error) {
+		if abortErr == nil {
+			abortErr = err
+			scope.signal.cancel(err)
+		}
+	}
+
+	for idx := 0; ; {
+		stopped := abortErr != nil || (policy == StopOnError && len(failures) > 0)
+		if !stopped && running < concurrency {
+			if scope.expired() {
+				abort(scope.deadlineErr)
+				continue
+			}
+			select {
+			case <-quit:
+				abort(ErrWorkerCanceledByUser)
+				continue
+			default:
+			}
+
+			w.mu.Lock()
+			w.updateProgress()
+			if idx < len(stage.tasks) {
+				task := stage.tasks[idx]
+				idx++
+				if _, kept := w.kept[task]; kept || w.isSkipped(task) || w.skipUnfit(task, scope.deadline) {
+					w.mu.Unlock()
+					continue
+				}
+				w.currSubTask = task
+				w.mu.Unlock()
+
+				running++
+				go func() {
+					done <- w.runTask(task, scope)
+				}()
+				continue
+			}
+			if running == 0 {
+				// the stage completes under the same lock, so no task can be added after the last one was taken
+				err := w.endStage(stage, policy, abortErr, failures)
+				w.mu.Unlock()
+				return err
+			}
+			w.mu.Unlock()
+		}
+		if running == 0 {
+			break
+		}
+
+		// wait for a running task to complete, stop and deadline are only recorded once
+		select {
+		case err := <-done:
+			running--
+			var taskErr *TaskError
+			if errors.As(err, &taskErr) {
+				failures = append(failures, err)
+			} else if err != nil {
+				abort(err)
+			}
+		case <-quit:
+			abort(ErrWorkerCanceledByUser)
+			quit = nil
+		case <-deadline:
+			abort(scope.deadlineErr)
+			deadline = nil
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.endStage(stage, policy, abortErr, failures)
+}
+
+// endStage Sets final state of stage and returns the error it ended with, caller must hold the worker lock
+func (w *Worker) endStage(stage *Stage, policy ErrorPolicy, abortErr error, failures []error) error {
+	err := abortErr
+	if err == nil && policy != IgnoreErrors && len(failures) == 1 {
+		err = failures[0]
+	} else if err == nil && policy != IgnoreErrors && len(failures) > 1 {
+		err = &StageError{Stage: stage.name, Errors: failures}
+	}
+
+	if err != nil {
+		stage.state = stateForError(err)
+	} else {
+		stage.state = Finished
+	}
+	return err
+}
@@ -2,21 +2,33 @@ package gotask
 
 import (
 	"errors"
+	"sync"
 )
 
 var (
-	ErrTaskRunning error = errors.New("task already running")
+	ErrTaskRunning  error = errors.New("task already running")
+	ErrTaskPanicked error = errors.New("task panicked")
 )
 
 // Task Struct for one task to handle inside the worker
 type Task struct {
-	name     string
-	state    State
-	progress Progress
-	weight   Weight
-	target   func(interface{}) error // target function of task, any return value must be handled using by input pointers
-	arg      interface{}
-	desc     string
+	mu           sync.Mutex // guards state and progress which are read by the worker while the task is running
+	name         string
+	state        State
+	progress     Progress
+	weight       Weight
+	target       func(interface{}) error          // target function of task, any return value must be handled using by input pointers
+	handleTarget func(*Handle, interface{}) error // alternative target function which gets a handle to the running worker
+	handle       *Handle                          // handle passed to handleTarget, set by worker before run
+	arg          interface{}
+	desc         string
+	tags         []string
+	labels       map[string]string
+	optional     bool
+	middleware   []Middleware
+	resources    map[string]int          // units required per resource name
+	err          error                   // error returned by target in last run
+	compensation func(interface{}) error // undoes work of task if a later task of the worker fails
 }
 
 // NewTask Factory method for creating a new task for proper initialition.
@@ -33,13 +45,84 @@ func NewTask(name string, weight Weight, desc string, target func(arg interface{
 	return &task
 }
 
+// NewHandleTask Factory method for creating a new task whose target gets a handle to the running worker.
+// The handle can be used to add subtasks to the worker while it is running.
+func NewHandleTask(name string, weight Weight, desc string, target func(h *Handle, arg interface{}) error, arg interface{}) *Task {
+	task := NewTask(name, weight, desc, nil, arg)
+	task.handleTarget = target
+	return task
+}
+
 // Run Runs task target function, this is called by worker
+// If the target panics, the task fails with ErrTaskPanicked and the panic is passed on, see Recover.
 func (t *Task) Run() {
+	t.mu.Lock()
 	t.progress = MinProgress
 	t.state = Running
-	t.target(t.arg)
-	t.state = Finished
-	t.progress = MaxProgress
+	t.err = nil
+	handle := t.handle
+	t.mu.Unlock()
+
+	var err error
+	returned := false
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !returned {
+			err = ErrTaskPanicked
+		}
+		t.err = err
+		if err != nil {
+			t.state = Failed
+			return
+		}
+		t.state = Finished
+		t.progress = MaxProgress
+	}()
+
+	if t.handleTarget != nil {
+		if handle == nil {
+			handle = &Handle{task: t}
+		}
+		err = t.handleTarget(handle, t.arg)
+	} else {
+		err = t.target(t.arg)
+	}
+	returned = true
+}
+
+// GetError Returns error returned by target in last run, nil if successful or not run yet
+func (t *Task) GetError() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// SetCompensation Sets function undoing the work of the task, returns task for chaining
+// The compensation is called with the task argument if the task finished, but the worker failed, timed out or was stopped later.
+func (t *Task) SetCompensation(compensation func(arg interface{}) error) *Task {
+	t.compensation = compensation
+	return t
+}
+
+// HasCompensation Checks if task has a compensation
+func (t *Task) HasCompensation() bool {
+	return t.compensation != nil
+}
+
+// Compensate Runs compensation of task, does nothing if task has no compensation
+func (t *Task) Compensate() error {
+	if t.compensation == nil {
+		return nil
+	}
+	return t.compensation(t.arg)
+}
+
+// bind Stores handle of worker which is about to run the task
+func (t *Task) bind(h *Handle) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handle = h
 }
 
 // GetName Returns Task name
@@ -49,12 +132,16 @@ func (t *Task) GetName() string {
 
 // GetState Returns Task state
 func (t *Task) GetState() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.state
 }
 
 // GetProgress Returns Task progress
 // Note: A task can be either not done or done, progress is not a float here
 func (t *Task) GetProgress() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return t.progress
 }
 
@@ -68,17 +155,93 @@ func (t *Task) GetDesc() string {
 	return t.desc
 }
 
+// SetTags Sets tags of task, returns task for chaining
+func (t *Task) SetTags(tags ...string) *Task {
+	t.tags = tags
+	return t
+}
+
+// GetTags Returns tags of task
+func (t *Task) GetTags() []string {
+	return t.tags
+}
+
+// HasTag Checks if task carries tag
+func (t *Task) HasTag(tag string) bool {
+	return containsTag(t.tags, tag)
+}
+
+// SetLabel Sets label key to value, returns task for chaining
+func (t *Task) SetLabel(key string, value string) *Task {
+	if t.labels == nil {
+		t.labels = make(map[string]string)
+	}
+	t.labels[key] = value
+	return t
+}
+
+// GetLabels Returns labels of task
+func (t *Task) GetLabels() map[string]string {
+	return t.labels
+}
+
+// SetOptional Marks task as optional, which is skipped if it can not finish in time, returns task for chaining
+// Note: Optional tasks are only skipped if the worker has SetSkipOptional enabled
+func (t *Task) SetOptional(optional bool) *Task {
+	t.optional = optional
+	return t
+}
+
+// IsOptional Checks if task is optional
+func (t *Task) IsOptional() bool {
+	return t.optional
+}
+
+// Use Adds middleware wrapped around the run of the task inside the middleware of the worker, returns task for chaining
+func (t *Task) Use(middleware ...Middleware) *Task {
+	t.middleware = append(t.middleware, middleware...)
+	return t
+}
+
+// GetMiddleware Returns middleware of task
+func (t *Task) GetMiddleware() []Middleware {
+	return t.middleware
+}
+
+// RequireResource Declares units of named resource the task requires while running, returns task for chaining
+// Note: Resources are only acquired if the worker has a ResourceManager set, units below one are set to one
+func (t *Task) RequireResource(name string, units int) *Task {
+	if units < 1 {
+		units = 1
+	}
+	if t.resources == nil {
+		t.resources = make(map[string]int)
+	}
+	t.resources[name] = units
+	return t
+}
+
+// GetResources Returns units required per resource name
+func (t *Task) GetResources() map[string]int {
+	return t.resources
+}
+
 // GetWorkLoad Returns task workload (progress times weight)
 func (t *Task) GetWorkLoad() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	return int(t.progress) * int(t.weight) / int(MaxProgress)
 }
 
 // AddProgress Adds value to current Task Progress until ProgressMaxVal is reached
 func (t *Task) Reset() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	if t.state == Running {
 		return ErrTaskRunning
 	}
 	t.state = Waiting
 	t.progress = MinProgress
+	t.err = nil
 	return nil
 }
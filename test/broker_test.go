@@ -0,0 +1,249 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// RemoteSquaring Agent handler squaring the number in the payload, reports half progress after a short delay
+func RemoteSquaring(job *gotask.AgentJob) ([]byte, error) {
+	var number int
+	if err := json.Unmarshal(job.GetPayload(), &number); err != nil {
+		return nil, err
+	}
+	if number < 0 {
+		return nil, errors.New("negative number")
+	}
+	time.Sleep(20 * time.Millisecond)
+	job.SetProgress(50)
+	select {
+	case <-job.Done():
+		return nil, nil
+	case <-time.After(time.Duration(number) * 10 * time.Millisecond):
+	}
+	return json.Marshal(number * number)
+}
+
+func createRemoteTask(broker *gotask.MemoryBroker, number int) *gotask.RemoteTask {
+	payload, _ := json.Marshal(number)
+	return gotask.NewRemoteTask("square", gotask.Weight(1), "squaring remotely", broker, "square", payload)
+}
+
+func remoteResult(t *testing.T, task *gotask.RemoteTask) int {
+	var result int
+	if err := json.Unmarshal(task.GetResult(), &result); err != nil {
+		t.Errorf("result not decodable: %v", err)
+	}
+	return result
+}
+
+func serveBroker(t *testing.T, broker *gotask.MemoryBroker) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go broker.Serve(listener)
+	return listener.Addr().String()
+}
+
+func TestRemoteTaskMemoryBroker(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	for _, name := range []string{"agent 0", "agent 1"} {
+		agent := gotask.NewAgent(name, broker).Handle("square", RemoteSquaring).SetHeartbeat(5 * time.Millisecond)
+		_ = agent.Start()
+		defer agent.Stop()
+	}
+
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("remote").SetConcurrency(2)
+	tasks := []*gotask.RemoteTask{createRemoteTask(broker, 3), createRemoteTask(broker, 4)}
+	_ = worker.AddTasks([]gotask.Runnable{tasks[0], tasks[1]})
+
+	_ = worker.Run(0)
+	time.Sleep(35 * time.Millisecond)
+	if prog := worker.GetProgress(); prog != 50 {
+		t.Errorf("progress not 50: %v", prog)
+	}
+	if name, _ := worker.GetCurrentTaskName(); name != "square" {
+		t.Errorf("current task not 'square': %v", name)
+	}
+	if agent := tasks[0].GetAgent(); agent != "agent 0" && agent != "agent 1" {
+		t.Errorf("task not run by an agent: %v", agent)
+	}
+
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if result := remoteResult(t, tasks[0]); result != 9 {
+		t.Errorf("result not 9: %v", result)
+	}
+	if result := remoteResult(t, tasks[1]); result != 16 {
+		t.Errorf("result not 16: %v", result)
+	}
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+}
+
+func TestRemoteTaskFailure(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	agent := gotask.NewAgent("agent", broker).Handle("square", RemoteSquaring)
+	_ = agent.Start()
+	defer agent.Stop()
+
+	worker := gotask.NewWorker("Workername")
+	task := createRemoteTask(broker, -1)
+	_ = worker.AddTask(task)
+
+	_ = worker.Run(0)
+	err := worker.Wait()
+	if !errors.Is(err, gotask.ErrWorkerTaskFailed) || !errors.Is(err, gotask.ErrBrokerTaskFailed) {
+		t.Errorf("err not ErrWorkerTaskFailed and ErrBrokerTaskFailed: %v", err)
+	}
+	if state := task.GetState(); state != gotask.Failed {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Failed), gotask.StateToString(state))
+	}
+
+	// a kind without handler fails as well
+	worker = gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewRemoteTask("cube", gotask.Weight(1), "", broker, "cube", nil))
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, gotask.ErrBrokerTaskFailed) {
+		t.Errorf("err not ErrBrokerTaskFailed: %v", err)
+	}
+}
+
+func TestRemoteTaskRPCBroker(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	address := serveBroker(t, broker)
+
+	client, err := gotask.DialBroker(address)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer client.Close()
+	agent := gotask.NewAgent("remote agent", client).Handle("square", RemoteSquaring).SetHeartbeat(5 * time.Millisecond)
+	if err := agent.Start(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	defer agent.Stop()
+
+	worker := gotask.NewWorker("Workername")
+	task := createRemoteTask(broker, 3)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	time.Sleep(35 * time.Millisecond)
+	if prog := worker.GetProgress(); prog != 50 {
+		t.Errorf("progress not 50: %v", prog)
+	}
+	if agent := task.GetAgent(); agent != "remote agent" {
+		t.Errorf("task not run by 'remote agent': %v", agent)
+	}
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if result := remoteResult(t, task); result != 9 {
+		t.Errorf("result not 9: %v", result)
+	}
+}
+
+func TestRemoteTaskRequeueOnDisconnect(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	address := serveBroker(t, broker)
+
+	client, err := gotask.DialBroker(address)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	lost := gotask.NewAgent("lost agent", client).Handle("square", RemoteSquaring).SetHeartbeat(5 * time.Millisecond)
+	_ = lost.Start()
+	defer lost.Stop()
+
+	worker := gotask.NewWorker("Workername")
+	task := createRemoteTask(broker, 10)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return task.GetAgent() == "lost agent" })
+
+	// the broker re-queues the task once the connection of the agent is lost
+	client.Close()
+	waitFor(t, func() bool { return broker.GetQueued() == 1 })
+	if prog := worker.GetProgress(); prog != gotask.MinProgress {
+		t.Errorf("progress not %v: %v", gotask.MinProgress, prog)
+	}
+
+	spare := gotask.NewAgent("spare agent", broker).Handle("square", RemoteSquaring)
+	_ = spare.Start()
+	defer spare.Stop()
+	waitFor(t, func() bool { return task.GetAgent() == "spare agent" })
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if result := remoteResult(t, task); result != 100 {
+		t.Errorf("result not 100: %v", result)
+	}
+}
+
+func TestRemoteTaskRequeueOnAgentTimeout(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	broker.SetAgentTimeout(40 * time.Millisecond)
+
+	worker := gotask.NewWorker("Workername")
+	task := createRemoteTask(broker, 1)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+
+	// an agent taking the task and going silent is dropped after the agent timeout
+	_ = broker.Register("silent agent")
+	if spec, _ := broker.Poll("silent agent", time.Second); spec == nil || spec.Name != "square" {
+		t.Errorf("polled task not 'square': %v", spec)
+	}
+	spare := gotask.NewAgent("spare agent", broker).Handle("square", RemoteSquaring).SetHeartbeat(5 * time.Millisecond)
+	_ = spare.Start()
+	defer spare.Stop()
+
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if agents := broker.GetAgents(); len(agents) != 1 || agents[0] != "spare agent" {
+		t.Errorf("agents not [spare agent]: %v", agents)
+	}
+	if err := broker.Report("silent agent", gotask.TaskReport{ID: "1", Done: true}); !errors.Is(err, gotask.ErrBrokerAgentUnknown) {
+		t.Errorf("err not ErrBrokerAgentUnknown: %v", err)
+	}
+}
+
+func TestRemoteTaskStop(t *testing.T) {
+
+	broker := gotask.NewMemoryBroker()
+	agent := gotask.NewAgent("agent", broker).Handle("square", RemoteSquaring).SetHeartbeat(5 * time.Millisecond)
+	_ = agent.Start()
+	defer agent.Stop()
+
+	worker := gotask.NewWorker("Workername")
+	task := createRemoteTask(broker, 20)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return task.GetAgent() == "agent" })
+
+	_ = worker.Stop()
+	if state := task.GetState(); state != gotask.Canceled {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+	// the agent gives up the task with its next heartbeat
+	waitFor(t, func() bool {
+		_, err := agent.GetCurrentTaskName()
+		return err != nil
+	})
+}
@@ -0,0 +1,141 @@
+package test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+func TestCommandTaskOutput(t *testing.T) {
+
+	dir := t.TempDir()
+	task := gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", `read line; echo "$line $GREETING"; pwd; echo warning >&2`).
+		SetEnv("GREETING=world").
+		SetDir(dir).
+		SetStdin(strings.NewReader("hello\n"))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+
+	if stdout := task.GetStdout(); stdout != "hello world\n"+dir+"\n" {
+		t.Errorf("stdout not 'hello world': %q", stdout)
+	}
+	if stderr := task.GetStderr(); stderr != "warning\n" {
+		t.Errorf("stderr not 'warning': %q", stderr)
+	}
+	if prog := task.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+}
+
+func TestCommandTaskExitCode(t *testing.T) {
+
+	task := gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", "echo broken pipe >&2; exit 3")
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+
+	err := worker.Wait()
+	var cmdErr *gotask.CommandError
+	if !errors.Is(err, gotask.ErrCommandFailed) || !errors.As(err, &cmdErr) {
+		t.Fatalf("err not CommandError: %v", err)
+	}
+	if cmdErr.ExitCode != 3 || cmdErr.Stderr != "broken pipe" {
+		t.Errorf("exit code and stderr not 3 and 'broken pipe': %v, %v", cmdErr.ExitCode, cmdErr.Stderr)
+	}
+	if code := task.GetExitCode(); code != 3 {
+		t.Errorf("exit code not 3: %v", code)
+	}
+	if state := task.GetState(); state != gotask.Failed {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Failed), gotask.StateToString(state))
+	}
+
+	missing := gotask.NewCommandTask("missing", gotask.Weight(1), "", "no-such-command-here")
+	missing.Run()
+	if err := missing.GetError(); err == nil || errors.Is(err, gotask.ErrCommandFailed) {
+		t.Errorf("err not start error: %v", err)
+	}
+}
+
+func TestCommandTaskProgress(t *testing.T) {
+
+	task := gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", `echo "copied 25%"; printf "copied 50%%\r"; sleep 0.1`).
+		SetProgressParser(gotask.PercentProgress)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+
+	waitFor(t, func() bool { return task.GetProgress() == 50 })
+	if prog := worker.GetProgress(); prog != 50 {
+		t.Errorf("progress not 50: %v", prog)
+	}
+	_ = worker.Wait()
+
+	if prog, ok := gotask.PercentProgress("step 2/4, 12.5 % done"); !ok || prog != 12.5 {
+		t.Errorf("progress not 12.5: %v", prog)
+	}
+	if _, ok := gotask.PercentProgress("no progress"); ok {
+		t.Errorf("line without percentage accepted")
+	}
+}
+
+func TestCommandTaskStop(t *testing.T) {
+
+	// the command ignores SIGTERM, so it is killed after the grace period
+	task := gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", `trap "echo terminating" TERM; echo started; while true; do sleep 0.01; done`).
+		SetGracePeriod(50 * time.Millisecond)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return task.GetStdout() == "started\n" })
+
+	start := time.Now()
+	_ = worker.Stop()
+	if dur := time.Since(start); dur < 50*time.Millisecond || dur > 500*time.Millisecond {
+		t.Errorf("stop did not wait for the grace period: %v", dur)
+	}
+	if state := task.GetState(); state != gotask.Canceled {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+	if stdout := task.GetStdout(); !strings.Contains(stdout, "terminating") {
+		t.Errorf("command did not receive SIGTERM: %q", stdout)
+	}
+
+	// a command exiting on SIGTERM ends at once on timeout
+	task = gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", "exec sleep 10")
+	worker = gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(50 * time.Millisecond)
+	if err := worker.Wait(); !errors.Is(err, gotask.ErrWorkerTimeoutReached) {
+		t.Errorf("err not ErrWorkerTimeoutReached: %v", err)
+	}
+	if dur, _ := worker.GetDuration(); dur > 0.5 {
+		t.Errorf("duration not below 0.5: %v", dur)
+	}
+}
+
+func TestCommandTaskStopWithChild(t *testing.T) {
+
+	// the shell waits for its child, which would keep the output pipes open if only the shell was terminated
+	task := gotask.NewCommandTask("shell", gotask.Weight(1), "", "sh", "-c", "echo started; sleep 3; echo done").
+		SetGracePeriod(50 * time.Millisecond)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return task.GetStdout() == "started\n" })
+
+	start := time.Now()
+	_ = worker.Stop()
+	if dur := time.Since(start); dur > 500*time.Millisecond {
+		t.Errorf("stop waited for the child of the command: %v", dur)
+	}
+	if state := task.GetState(); state != gotask.Canceled {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+var errDeploy = errors.New("deploy failed")
+
+// Failing test function which always returns an error
+func Failing(arg interface{}) error {
+	return errDeploy
+}
+
+// compensationLog Records order in which compensations were called
+type compensationLog struct {
+	mu    sync.Mutex
+	order []string
+}
+
+// Undo test compensation which records its argument
+func (l *compensationLog) Undo(arg interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.order = append(l.order, arg.(string))
+	return nil
+}
+
+func TestTaskFailureStopsWorker(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "Failing", Failing, nil))
+	_ = worker.AddTask(gotask.NewTask("task 2", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1))
+
+	worker.Run(0)
+	err := worker.Wait()
+	if !errors.Is(err, gotask.ErrWorkerTaskFailed) || !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v and %v, got: %v", gotask.ErrWorkerTaskFailed, errDeploy, err)
+	}
+	var taskErr *gotask.TaskError
+	if !errors.As(err, &taskErr) || taskErr.Task != "task 1" {
+		t.Errorf("expected task error of 'task 1', got: %v", err)
+	}
+	if state := worker.GetState(); state != gotask.Failed {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Failed), gotask.StateToString(state))
+	}
+	subTasks := worker.GetSubtasks()
+	if subTasks[0].GetState() != gotask.Finished || subTasks[1].GetState() != gotask.Failed || subTasks[2].GetState() != gotask.Waiting {
+		t.Errorf("unexpected task states: %v, %v, %v", gotask.StateToString(subTasks[0].GetState()), gotask.StateToString(subTasks[1].GetState()), gotask.StateToString(subTasks[2].GetState()))
+	}
+	if err := worker.Run(0); err != gotask.ErrWokerFinished {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWokerFinished, err)
+	}
+}
+
+func TestCompensationOnFailure(t *testing.T) {
+
+	log := &compensationLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("step 0", gotask.Weight(1), "", func(interface{}) error { return nil }, "step 0").SetCompensation(log.Undo))
+	_ = worker.AddTask(gotask.NewTask("step 1", gotask.Weight(1), "", func(interface{}) error { return nil }, "step 1"))
+	_ = worker.AddTask(gotask.NewTask("step 2", gotask.Weight(1), "", func(interface{}) error { return nil }, "step 2").SetCompensation(log.Undo))
+	_ = worker.AddTask(gotask.NewTask("step 3", gotask.Weight(1), "", Failing, "step 3").SetCompensation(log.Undo))
+
+	worker.Run(0)
+	err := worker.Wait()
+
+	var compErr *gotask.CompensationError
+	if !errors.As(err, &compErr) {
+		t.Fatalf("expected compensation error, got: %v", err)
+	}
+	if !errors.Is(err, errDeploy) || compErr.Failed() {
+		t.Errorf("expected original error %v and no failed compensation, got: %v", errDeploy, err)
+	}
+	if len(log.order) != 2 || log.order[0] != "step 2" || log.order[1] != "step 0" {
+		t.Errorf("expected compensation order [step 2 step 0], got: %v", log.order)
+	}
+	results := worker.GetCompensations()
+	if len(results) != 2 || results[0].Task != "step 2" || results[0].Err != nil {
+		t.Errorf("unexpected compensation results: %v", results)
+	}
+
+	// a successful run does not compensate
+	worker = gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("step 0", gotask.Weight(1), "", func(interface{}) error { return nil }, "step 0").SetCompensation(log.Undo))
+	worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if results := worker.GetCompensations(); len(results) != 0 {
+		t.Errorf("unexpected compensation results: %v", results)
+	}
+}
+
+func TestCompensationOnStop(t *testing.T) {
+
+	log := &compensationLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("step 0", gotask.Weight(1), "", Sleeping, 10).SetCompensation(func(interface{}) error { return log.Undo("step 0") }))
+	_ = worker.AddTask(gotask.NewTask("step 1", gotask.Weight(1), "", Sleeping, 50).SetCompensation(func(interface{}) error { return errDeploy }))
+	_ = worker.AddTask(gotask.NewTask("step 2", gotask.Weight(1), "", Sleeping, 50).SetCompensation(func(interface{}) error { return log.Undo("step 2") }))
+
+	worker.Run(0)
+	time.Sleep(20 * time.Millisecond)
+	worker.Stop()
+	err := worker.Wait()
+
+	if !errors.Is(err, gotask.ErrWorkerCanceledByUser) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerCanceledByUser, err)
+	}
+	var compErr *gotask.CompensationError
+	if !errors.As(err, &compErr) || !compErr.Failed() || len(compErr.Results) != 2 {
+		t.Fatalf("expected two compensations with one failed, got: %v", err)
+	}
+	if compErr.Results[0].Task != "step 1" || compErr.Results[0].Err != errDeploy || compErr.Results[1].Task != "step 0" {
+		t.Errorf("unexpected compensation results: %v", compErr.Results)
+	}
+	if len(log.order) != 1 || log.order[0] != "step 0" {
+		t.Errorf("expected compensation order [step 0], got: %v", log.order)
+	}
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+func TestDryRun(t *testing.T) {
+
+	log := &runLog{}
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("build").SetConcurrency(2)
+	_ = worker.AddTask(gotask.NewTask("a", gotask.Weight(1), "", log.Run, "a"))
+	_ = worker.AddTask(gotask.NewTask("b", gotask.Weight(3), "", log.Run, "b"))
+	_ = worker.AddTask(gotask.NewTask("c", gotask.Weight(1), "", log.Run, "c"))
+	worker.AddStage("deploy")
+	_ = worker.AddTask(gotask.NewTask("d", gotask.Weight(2), "", log.Run, "d").SetTags("deploy"))
+	_ = worker.AddTask(gotask.NewTask("e", gotask.Weight(5), "", log.Run, "e").SetTags("slow"))
+	_ = worker.SetSelection(gotask.NewSelection().ExcludeTags("slow"))
+
+	result, err := worker.DryRun(6 * time.Second)
+	if err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if result.TotalWeight != 7 || result.ProjectedDuration != 5*time.Second || !result.Fits {
+		t.Errorf("unexpected projection: %v, %v, %v", result.TotalWeight, result.ProjectedDuration, result.Fits)
+	}
+
+	var events []string
+	for _, event := range result.Events {
+		events = append(events, fmt.Sprintf("%v %s %s %s", event.At.Seconds(), event.Kind, event.Name, event.State))
+	}
+	expected := []string{
+		"0 worker Workername RUNNING",
+		"0 stage build RUNNING",
+		"0 task a RUNNING",
+		"0 task b RUNNING",
+		"1 task a FINISHED",
+		"1 task c RUNNING",
+		"2 task c FINISHED",
+		"3 task b FINISHED",
+		"3 stage build FINISHED",
+		"3 stage deploy RUNNING",
+		"3 task d RUNNING",
+		"3 task e SKIPPED",
+		"5 task d FINISHED",
+		"5 stage deploy FINISHED",
+		"5 worker Workername FINISHED",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("unexpected events:\n%v\nexpected:\n%v", events, expected)
+	}
+
+	// no task was invoked and the worker is unchanged
+	if runs := log.Runs(); len(runs) != 0 {
+		t.Errorf("tasks invoked by dry run: %v", runs)
+	}
+	if state := worker.GetState(); state != gotask.Waiting {
+		t.Errorf("worker state not equal to %v: %v", gotask.Waiting, state)
+	}
+	if workload := worker.GetTotalWorkLoad(); workload != 7 {
+		t.Errorf("total workload not 7: %v", workload)
+	}
+	if result, _ := worker.DryRun(4 * time.Second); result.Fits {
+		t.Errorf("run of 5s fits timeout of 4s")
+	}
+}
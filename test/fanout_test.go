@@ -0,0 +1,125 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// Squaring test function which sleeps 20ms and returns square of item
+func Squaring(item interface{}) (interface{}, error) {
+	time.Sleep(20 * time.Millisecond)
+	value := item.(int)
+	if value < 0 {
+		return nil, errDeploy
+	}
+	return value * value, nil
+}
+
+// sum test reduce function adding up integer results
+func sum(acc interface{}, result interface{}) interface{} {
+	return acc.(int) + result.(int)
+}
+
+func TestFanOutReduce(t *testing.T) {
+
+	fanOut := gotask.NewFanOut("square", "Squaring items", []interface{}{1, 2, 3, 4}, Squaring).
+		SetItemName(func(idx int, item interface{}) string { return fmt.Sprintf("square %d", item) }).
+		SetItemWeight(func(item interface{}) gotask.Weight { return gotask.Weight(item.(int)) }).
+		SetReduce(0, sum)
+
+	if weight := fanOut.GetWeight(); weight != 10 {
+		t.Errorf("fan out weight not 10: %v", weight)
+	}
+	if name := fanOut.GetChildren()[2].GetName(); name != "square 3" {
+		t.Errorf("child name not 'square 3': %v", name)
+	}
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(fanOut)
+	worker.Run(0)
+	time.Sleep(50 * time.Millisecond) // children 1 and 2 finished, child 3 running
+	if prog := worker.GetProgress(); prog < 29.9 || prog > 30.1 {
+		t.Errorf("progress not 30: %v", prog)
+	}
+
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if result := fanOut.GetResult(); result != 30 {
+		t.Errorf("reduced result not 30: %v", result)
+	}
+	if results := fanOut.GetResults(); len(results) != 4 || results[3] != 16 {
+		t.Errorf("unexpected results: %v", results)
+	}
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+}
+
+func TestFanOutConcurrent(t *testing.T) {
+
+	items := make([]interface{}, 6)
+	for i := range items {
+		items[i] = i
+	}
+	fanOut := gotask.NewFanOut("square", "Squaring items", items, Squaring).SetConcurrency(3).SetReduce(0, sum)
+
+	start := time.Now()
+	fanOut.Run()
+	if elapsed := time.Since(start); elapsed > 60*time.Millisecond {
+		t.Errorf("concurrent run took longer than two rounds: %v", elapsed)
+	}
+	if result := fanOut.GetResult(); result != 55 {
+		t.Errorf("reduced result not 55: %v", result)
+	}
+	if state := fanOut.GetState(); state != gotask.Finished {
+		t.Errorf("fan out state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+}
+
+func TestFanOutFailure(t *testing.T) {
+
+	fanOut := gotask.NewFanOut("square", "Squaring items", []interface{}{1, -1, 2}, Squaring).SetReduce(0, sum)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(fanOut)
+
+	worker.Run(0)
+	err := worker.Wait()
+	if !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", errDeploy, err)
+	}
+	if state := fanOut.GetState(); state != gotask.Failed {
+		t.Errorf("fan out state not equal to %v: %v", gotask.StateToString(gotask.Failed), gotask.StateToString(state))
+	}
+	children := fanOut.GetChildren()
+	if children[1].GetState() != gotask.Failed || children[2].GetState() != gotask.Waiting {
+		t.Errorf("unexpected child states: %v, %v", gotask.StateToString(children[1].GetState()), gotask.StateToString(children[2].GetState()))
+	}
+	if result := fanOut.GetResult(); result != nil {
+		t.Errorf("result of failed fan out not nil: %v", result)
+	}
+}
+
+func TestFanOutStop(t *testing.T) {
+
+	fanOut := gotask.NewFanOut("square", "Squaring items", []interface{}{1, 2, 3, 4, 5}, Squaring)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(fanOut)
+
+	worker.Run(0)
+	time.Sleep(30 * time.Millisecond)
+	worker.Stop()
+	if err := worker.Wait(); err != gotask.ErrWorkerCanceledByUser {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerCanceledByUser, err)
+	}
+	if state := fanOut.GetState(); state != gotask.Canceled {
+		t.Errorf("fan out state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+	if weight := worker.GetRemainingWorkLoad(); weight != 3 {
+		t.Errorf("remaining weight not equal to 3: %v", weight)
+	}
+}
@@ -0,0 +1,109 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+func TestFakeClockTimeout(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	recorder := gotasktest.NewRecorder()
+	task := gotasktest.NewFakeTask("task 0", gotask.Weight(1))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetClock(clock)
+	_ = worker.Use(recorder.Middleware())
+	_ = worker.AddTask(task)
+
+	_ = worker.Run(10 * time.Second)
+	<-task.Started()
+	gotasktest.WaitFor(t, func() bool { return clock.GetWaiters() == 1 })
+	clock.Advance(4 * time.Second)
+	if duration, _ := worker.GetDuration(); duration != 4 {
+		t.Errorf("duration not 4s: %v", duration)
+	}
+	if remaining, _ := worker.GetRemainingTime(); remaining != 6 {
+		t.Errorf("remaining time not 6s: %v", remaining)
+	}
+
+	clock.Advance(6 * time.Second)
+	if err := worker.Wait(); !errors.Is(err, gotask.ErrWorkerTimeoutReached) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerTimeoutReached, err)
+	}
+	gotasktest.AssertState(t, worker, gotask.TimeoutReached)
+	recorder.AssertStates(t, "task 0", gotask.Running, gotask.Failed)
+	if duration := worker.GetReport().Tasks[0].Duration; duration != 10*time.Second {
+		t.Errorf("task duration not 10s: %v", duration)
+	}
+}
+
+func TestFakeTaskRelease(t *testing.T) {
+
+	first := gotasktest.NewFakeTask("task 0", gotask.Weight(1))
+	second := gotasktest.NewFakeTask("task 1", gotask.Weight(1))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTasks([]gotask.Runnable{first, second})
+	second.Release() // outcomes can be given before the task is started
+
+	_ = worker.Run(0)
+	<-first.Started()
+	gotasktest.AssertState(t, first, gotask.Running)
+	gotasktest.AssertState(t, second, gotask.Waiting)
+	if progress := worker.GetProgress(); progress != 0 {
+		t.Errorf("progress not 0: %v", progress)
+	}
+	first.Release()
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	gotasktest.AssertState(t, second, gotask.Finished)
+}
+
+func TestFakeTaskRetry(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	recorder := gotasktest.NewRecorder()
+	task := gotasktest.NewFakeTask("task 0", gotask.Weight(1))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetClock(clock)
+	_ = worker.Use(gotask.Retry(2, time.Minute), recorder.Middleware())
+	_ = worker.AddTask(task)
+	task.Fail(errDeploy)
+	task.Release()
+
+	_ = worker.Run(0)
+	// the retry waits for the delay on the clock of the worker
+	gotasktest.WaitFor(t, func() bool { return clock.GetWaiters() == 1 })
+	clock.Advance(time.Minute)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	recorder.AssertStates(t, "task 0", gotask.Running, gotask.Failed, gotask.Running, gotask.Finished)
+	if runs := task.GetRuns(); runs != 2 {
+		t.Errorf("runs not 2: %v", runs)
+	}
+}
+
+func TestFakeTaskStartedNotReceived(t *testing.T) {
+
+	// runs of a task whose start is never received do not block
+	task := gotasktest.NewFakeTask("task 0", gotask.Weight(1))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	for run := 0; run < 100; run++ {
+		_ = worker.Reset()
+		task.Release()
+		_ = worker.Run(time.Second)
+		if err := worker.Wait(); err != nil {
+			t.Fatalf("err not nil: %v", err)
+		}
+	}
+	if runs := task.GetRuns(); runs != 100 {
+		t.Errorf("runs not 100: %v", runs)
+	}
+	<-task.Started()
+}
@@ -0,0 +1,169 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+var errCleanup = errors.New("cleanup failed")
+
+// record Records name like a run task
+func (l *runLog) record(name string) error {
+	return l.Run(name)
+}
+
+// setHooks helper function setting all hooks of worker, recorded by log
+func setHooks(worker *gotask.Worker, log *runLog) {
+	_ = worker.SetBeforeAll(func() error { return log.record("before all") })
+	_ = worker.SetAfterAll(func() error { return log.record("after all") })
+	_ = worker.SetBeforeEach(func(task gotask.Runnable) error { return log.record("before " + task.GetName()) })
+	_ = worker.SetAfterEach(func(task gotask.Runnable) error { return log.record("after " + task.GetName()) })
+	_ = worker.AddFinally(gotask.NewTask("cleanup", gotask.Weight(1), "", log.Run, "cleanup"))
+}
+
+func TestHooks(t *testing.T) {
+
+	log := &runLog{}
+	worker := createRunLogWorker(log, "task 0", "task 1")
+	setHooks(worker, log)
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	expected := "[before all before task 0 task 0 after task 0 before task 1 task 1 after task 1 after all cleanup]"
+	if runs := fmtRuns(log.Runs()); runs != expected {
+		t.Errorf("unexpected order:\n%v\nexpected:\n%v", runs, expected)
+	}
+}
+
+func TestHooksOnFailure(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"before all": true}}
+	worker := createRunLogWorker(log, "task 0")
+	setHooks(worker, log)
+	_ = worker.Run(0)
+	err := worker.Wait()
+	var hookErr *gotask.HookError
+	if !errors.Is(err, gotask.ErrWorkerHookFailed) || !errors.As(err, &hookErr) || hookErr.Hook != "BeforeAll" {
+		t.Errorf("expected BeforeAll HookError, got: %v", err)
+	}
+	if state := worker.GetState(); state != gotask.Failed {
+		t.Errorf("worker state not equal to %v: %v", gotask.Failed, state)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[before all after all cleanup]" {
+		t.Errorf("run not [before all after all cleanup]: %v", runs)
+	}
+
+	// a failing BeforeEach hook fails its task without running it
+	log = &runLog{failing: map[string]bool{"before task 0": true}}
+	worker = createRunLogWorker(log, "task 0", "task 1")
+	setHooks(worker, log)
+	_ = worker.Run(0)
+	err = worker.Wait()
+	if !errors.Is(err, gotask.ErrWorkerTaskFailed) || !errors.Is(err, gotask.ErrWorkerHookFailed) {
+		t.Errorf("expected TaskError of BeforeEach, got: %v", err)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[before all before task 0 after all cleanup]" {
+		t.Errorf("run not [before all before task 0 after all cleanup]: %v", runs)
+	}
+}
+
+func TestFinallyOnStop(t *testing.T) {
+
+	log := &runLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("sleeping", gotask.Weight(1), "", Sleeping, 50))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "", log.Run, "task 1"))
+	_ = worker.AddFinally(gotask.NewTask("cleanup", gotask.Weight(1), "", log.Run, "cleanup"))
+	_ = worker.Run(0)
+	_ = worker.Stop()
+	if runs := fmtRuns(log.Runs()); runs != "[cleanup]" {
+		t.Errorf("run not [cleanup]: %v", runs)
+	}
+}
+
+func TestHookErrorsReportedSeparately(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"after task 0": true}}
+	worker := createRunLogWorker(log, "task 0")
+	setHooks(worker, log)
+	_ = worker.AddFinally(gotask.NewTask("failing cleanup", gotask.Weight(1), "", func(arg interface{}) error { return errCleanup }, nil))
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	errs := worker.GetHookErrors()
+	if len(errs) != 2 || errs[0].Hook != "AfterEach" || errs[0].Task != "task 0" || errs[1].Hook != "Finally" || !errors.Is(errs[1], errCleanup) {
+		t.Errorf("unexpected hook errors: %v", errs)
+	}
+}
+
+func TestFinallyTimeout(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.AddFinally(gotask.NewTask("hanging cleanup", gotask.Weight(1), "", Sleeping, 500))
+	_ = worker.SetFinallyTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("wait not bounded by finally timeout: %v", elapsed)
+	}
+	if errs := worker.GetHookErrors(); len(errs) != 1 || !errors.Is(errs[0], gotask.ErrFinallyTimeoutReached) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrFinallyTimeoutReached, errs)
+	}
+}
+
+func TestWorkerBusyWhileFinishing(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.AddFinally(gotask.NewTask("cleanup", gotask.Weight(1), "", Sleeping, 100))
+	_ = worker.Run(0)
+	for worker.IsRunning() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := worker.Reset(); err != gotask.ErrWorkerRunning {
+		t.Errorf("expected err %v while finally task runs, got: %v", gotask.ErrWorkerRunning, err)
+	}
+	if err := worker.Resume(0); err != gotask.ErrWorkerRunning {
+		t.Errorf("expected err %v while finally task runs, got: %v", gotask.ErrWorkerRunning, err)
+	}
+	_ = worker.Wait()
+	if err := worker.Reset(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+}
+
+func TestAbandonedFinallyTask(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.AddFinally(gotask.NewTask("hanging cleanup", gotask.Weight(1), "", Sleeping, 50))
+	_ = worker.SetFinallyTimeout(10 * time.Millisecond)
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	// the abandoned finally task must not touch the worker once it was reset and run again
+	if err := worker.Reset(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	_ = worker.SetFinallyTimeout(time.Second)
+	_ = worker.Run(0)
+	time.Sleep(80 * time.Millisecond)
+	_ = worker.Wait()
+	if state := worker.GetState(); state != gotask.Finished {
+		t.Errorf("worker state not equal to %v: %v", gotask.Finished, state)
+	}
+}
+
+// fmtRuns Formats recorded names for comparison
+func fmtRuns(runs []string) string {
+	return fmt.Sprint(runs)
+}
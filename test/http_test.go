@@ -0,0 +1,252 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+var httpContent = bytes.Repeat([]byte("0123456789"), 1000)
+
+func httpChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// httpETag ETag of httpContent served by rangeRecorder
+const httpETag = `"v1"`
+
+// rangeRecorder Serves httpContent with range support and records the range headers of all requests
+type rangeRecorder struct {
+	mu     sync.Mutex
+	ranges []string
+}
+
+func (r *rangeRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	r.ranges = append(r.ranges, req.Header.Get("Range"))
+	r.mu.Unlock()
+	w.Header().Set("ETag", httpETag)
+	http.ServeContent(w, req, "content.txt", time.Time{}, bytes.NewReader(httpContent))
+}
+
+// writePart Writes part file of an interrupted download of content with validator
+func writePart(dest string, content []byte, validator string) {
+	_ = ioutil.WriteFile(dest+".part", content, 0644)
+	_ = ioutil.WriteFile(dest+".part.validator", []byte(validator), 0644)
+}
+
+func TestHTTPTaskRequest(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(req.Method + " " + req.Header.Get("X-Token") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	task := gotask.NewHTTPTask("post", gotask.Weight(1), "", http.MethodPost, server.URL).
+		SetHeader("X-Token", "secret").
+		SetBody([]byte("payload")).
+		SetExpectedStatus(http.StatusCreated)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if body := string(task.GetBody()); body != "POST secret payload" {
+		t.Errorf("body not 'POST secret payload': %v", body)
+	}
+	if code := task.GetStatusCode(); code != http.StatusCreated {
+		t.Errorf("status code not 201: %v", code)
+	}
+}
+
+func TestHTTPTaskStatus(t *testing.T) {
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	task := gotask.NewHTTPTask("get", gotask.Weight(1), "", http.MethodGet, server.URL)
+	task.Run()
+	var statusErr *gotask.HTTPStatusError
+	if err := task.GetError(); !errors.Is(err, gotask.ErrHTTPStatus) || !errors.As(err, &statusErr) {
+		t.Fatalf("err not HTTPStatusError: %v", err)
+	}
+	if statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("status code not 404: %v", statusErr.StatusCode)
+	}
+	if state := task.GetState(); state != gotask.Failed {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Failed), gotask.StateToString(state))
+	}
+}
+
+func TestHTTPTaskProgress(t *testing.T) {
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(httpContent)))
+		_, _ = w.Write(httpContent[:len(httpContent)/4])
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write(httpContent[len(httpContent)/4:])
+	}))
+	defer server.Close()
+
+	task := gotask.NewHTTPTask("get", gotask.Weight(1), "", http.MethodGet, server.URL).
+		SetChecksum(sha256.New, httpChecksum(httpContent))
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+
+	waitFor(t, func() bool { return task.GetProgress() == 25 })
+	if received, total := task.GetReceived(); received != 2500 || total != 10000 {
+		t.Errorf("received not 2500 of 10000: %v of %v", received, total)
+	}
+	close(release)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if !bytes.Equal(task.GetBody(), httpContent) {
+		t.Errorf("body not equal to content")
+	}
+}
+
+func TestHTTPTaskResumeDownload(t *testing.T) {
+
+	recorder := &rangeRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	// the first half was downloaded by an earlier, interrupted run
+	dest := filepath.Join(t.TempDir(), "content.txt")
+	writePart(dest, httpContent[:5000], httpETag)
+
+	task := gotask.NewDownloadTask("download", gotask.Weight(1), "", server.URL, dest).
+		SetChecksum(sha256.New, httpChecksum(httpContent))
+	task.Run()
+	if err := task.GetError(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if content, _ := ioutil.ReadFile(dest); !bytes.Equal(content, httpContent) {
+		t.Errorf("downloaded file not equal to content")
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("part file not removed: %v", err)
+	}
+	if len(recorder.ranges) != 1 || recorder.ranges[0] != "bytes=5000-" {
+		t.Errorf("range not 'bytes=5000-': %v", recorder.ranges)
+	}
+	if code := task.GetStatusCode(); code != http.StatusPartialContent {
+		t.Errorf("status code not 206: %v", code)
+	}
+	if received, total := task.GetReceived(); received != 10000 || total != 10000 {
+		t.Errorf("received not 10000 of 10000: %v of %v", received, total)
+	}
+}
+
+func TestHTTPTaskResumeChangedDownload(t *testing.T) {
+
+	server := httptest.NewServer(&rangeRecorder{})
+	defer server.Close()
+	dir := t.TempDir()
+
+	// the part belongs to an older version of the remote file, which is downloaded again as a whole
+	tests := []struct {
+		name      string
+		part      []byte
+		validator string
+	}{
+		{"changed", []byte("old content"), `"v0"`},
+		{"no validator", []byte("old content"), ""},
+		{"longer", append(append([]byte(nil), httpContent...), "old content"...), httpETag},
+		{"complete", httpContent, httpETag},
+	}
+	for _, test := range tests {
+		dest := filepath.Join(dir, test.name+".txt")
+		writePart(dest, test.part, test.validator)
+		task := gotask.NewDownloadTask("download", gotask.Weight(1), "", server.URL, dest)
+		task.Run()
+		if err := task.GetError(); err != nil {
+			t.Errorf("%v: err not nil: %v", test.name, err)
+		}
+		if content, _ := ioutil.ReadFile(dest); !bytes.Equal(content, httpContent) {
+			t.Errorf("%v: downloaded file not equal to content", test.name)
+		}
+		if _, err := os.Stat(dest + ".part.validator"); !os.IsNotExist(err) {
+			t.Errorf("%v: validator file not removed: %v", test.name, err)
+		}
+	}
+}
+
+func TestHTTPTaskChecksumMismatch(t *testing.T) {
+
+	server := httptest.NewServer(&rangeRecorder{})
+	defer server.Close()
+
+	dest := filepath.Join(t.TempDir(), "content.txt")
+	task := gotask.NewDownloadTask("download", gotask.Weight(1), "", server.URL, dest).
+		SetChecksum(sha256.New, httpChecksum([]byte("other content")))
+	task.Run()
+	if err := task.GetError(); !errors.Is(err, gotask.ErrHTTPChecksumMismatch) {
+		t.Errorf("err not ErrHTTPChecksumMismatch: %v", err)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("part file not removed: %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("destination file created: %v", err)
+	}
+}
+
+func TestHTTPTaskStop(t *testing.T) {
+
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(httpContent)))
+		w.Header().Set("ETag", httpETag)
+		_, _ = w.Write(httpContent[:100])
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	dest := filepath.Join(t.TempDir(), "content.txt")
+	task := gotask.NewDownloadTask("download", gotask.Weight(1), "", server.URL, dest)
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	waitFor(t, func() bool {
+		received, _ := task.GetReceived()
+		return received == 100
+	})
+
+	_ = worker.Stop()
+	if state := task.GetState(); state != gotask.Canceled {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+	// the received part is kept to resume the download in the next run
+	if info, err := os.Stat(dest + ".part"); err != nil || info.Size() != 100 {
+		t.Errorf("part file not kept: %v", err)
+	}
+	if validator, _ := ioutil.ReadFile(dest + ".part.validator"); string(validator) != httpETag {
+		t.Errorf("validator not %v: %q", httpETag, validator)
+	}
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/morgadow/gotask"
+)
+
+// junitSuite Parsed JUnit testsuite, only holding what the tests check
+type junitSuite struct {
+	Name     string `xml:"name,attr"`
+	Tests    int    `xml:"tests,attr"`
+	Failures int    `xml:"failures,attr"`
+	Skipped  int    `xml:"skipped,attr"`
+	Cases    []struct {
+		Name    string `xml:"name,attr"`
+		Time    string `xml:"time,attr"`
+		Failure *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"failure"`
+		Skipped *struct {
+			Message string `xml:"message,attr"`
+		} `xml:"skipped"`
+		SystemOut string `xml:"system-out"`
+	} `xml:"testcase"`
+}
+
+func TestExportJUnit(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewHandleTask("printing", gotask.Weight(1), "", Printing, 2))
+	_ = worker.AddTask(gotask.NewTask("failing", gotask.Weight(1), "", Failing, nil))
+	_ = worker.AddTask(gotask.NewTask("sleeping", gotask.Weight(1), "", Sleeping, 1))
+
+	var buf bytes.Buffer
+	if err := worker.ExportJUnit(&buf); err != gotask.ErrWorkerNotStarted {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerNotStarted, err)
+	}
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if err := worker.ExportJUnit(&buf); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml") {
+		t.Errorf("xml header missing: %s", buf.String())
+	}
+
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if suite.Name != "Workername" || suite.Tests != 3 || suite.Failures != 1 || suite.Skipped != 1 || len(suite.Cases) != 3 {
+		t.Fatalf("unexpected testsuite: %+v", suite)
+	}
+	printing, failing, sleeping := suite.Cases[0], suite.Cases[1], suite.Cases[2]
+	if printing.Name != "printing" || printing.SystemOut != "line 0\nline 1\n" || printing.Failure != nil || printing.Time < "0.020" {
+		t.Errorf("unexpected testcase of finished task: %+v", printing)
+	}
+	if failing.Failure == nil || failing.Failure.Message != "deploy failed" {
+		t.Errorf("testcase of failed task without failure: %+v", failing)
+	}
+	if sleeping.Skipped == nil || sleeping.Skipped.Message != "task was not started" {
+		t.Errorf("testcase of task not started not skipped: %+v", sleeping)
+	}
+}
+
+func TestExportJUnitSkipReason(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("selected", gotask.Weight(1), "", Noop, nil).SetTags("build"))
+	_ = worker.AddTask(gotask.NewTask("bypassed", gotask.Weight(1), "", Noop, nil).SetTags("build"))
+	_ = worker.AddTask(gotask.NewTask("deselected", gotask.Weight(1), "", Noop, nil))
+	_ = worker.SetSelection(gotask.NewSelection().IncludeTags("build"))
+	_ = worker.Use(func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			if h.GetTask().GetName() == "bypassed" {
+				return nil
+			}
+			return next(h)
+		}
+	})
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	var buf bytes.Buffer
+	_ = worker.ExportJUnit(&buf)
+	var suite junitSuite
+	if err := xml.Unmarshal(buf.Bytes(), &suite); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if suite.Skipped != 2 || len(suite.Cases) != 3 || suite.Cases[0].Skipped != nil {
+		t.Fatalf("unexpected testsuite: %+v", suite)
+	}
+	if bypassed := suite.Cases[1]; bypassed.Skipped == nil || bypassed.Skipped.Message != "task was not run by middleware" {
+		t.Errorf("testcase of bypassed task not skipped by middleware: %+v", bypassed)
+	}
+	if deselected := suite.Cases[2]; deselected.Skipped == nil || deselected.Skipped.Message != "task was not selected" {
+		t.Errorf("testcase of deselected task not skipped as not selected: %+v", deselected)
+	}
+}
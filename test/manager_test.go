@@ -0,0 +1,120 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+func createManager(maxRunning int, amount int) *gotask.Manager {
+	manager := gotask.NewManager(maxRunning)
+	for idx := 0; idx < amount; idx++ {
+		worker := gotask.NewWorker("Workername")
+		_ = worker.AddTask(gotask.NewTask("Taskname", gotask.Weight(1), "", Sleeping, 30))
+		_ = manager.Register(string(rune('a'+idx)), worker)
+	}
+	return manager
+}
+
+func TestManagerRegister(t *testing.T) {
+
+	manager := createManager(0, 2)
+	if err := manager.Register("a", gotask.NewWorker("Workername")); !errors.Is(err, gotask.ErrManagerIDExists) {
+		t.Errorf("err not ErrManagerIDExists: %v", err)
+	}
+	if _, err := manager.Get("x"); !errors.Is(err, gotask.ErrManagerIDUnknown) {
+		t.Errorf("err not ErrManagerIDUnknown: %v", err)
+	}
+	if err := manager.Start("x", 0); !errors.Is(err, gotask.ErrManagerIDUnknown) {
+		t.Errorf("err not ErrManagerIDUnknown: %v", err)
+	}
+
+	_ = manager.Start("a", 0)
+	if err := manager.Unregister("a"); !errors.Is(err, gotask.ErrWorkerRunning) {
+		t.Errorf("err not ErrWorkerRunning: %v", err)
+	}
+	if err := manager.Unregister("b"); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if ids := manager.GetIDs(); len(ids) != 1 || ids[0] != "a" {
+		t.Errorf("ids not [a]: %v", ids)
+	}
+	manager.Wait()
+	if err := manager.Unregister("a"); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+}
+
+func TestManagerMaxRunning(t *testing.T) {
+
+	manager := createManager(2, 4)
+	for _, id := range manager.GetIDs() {
+		if err := manager.Start(id, 0); err != nil {
+			t.Errorf("err not nil: %v", err)
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if amount := manager.GetAmountRunning(); amount != 2 {
+		t.Errorf("amount running not 2: %v", amount)
+	}
+	if running := manager.Filter(gotask.Running); len(running) != 2 || running[0] != "a" || running[1] != "b" {
+		t.Errorf("running not [a b]: %v", running)
+	}
+	if queued := manager.GetQueued(); len(queued) != 2 || queued[0] != "c" || queued[1] != "d" {
+		t.Errorf("queued not [c d]: %v", queued)
+	}
+	if prog := manager.GetProgress(); prog != gotask.MinProgress {
+		t.Errorf("progress not %v: %v", gotask.MinProgress, prog)
+	}
+
+	// queued workers start once the first ones finished
+	time.Sleep(30 * time.Millisecond)
+	if running := manager.Filter(gotask.Running); len(running) != 2 || running[0] != "c" || running[1] != "d" {
+		t.Errorf("running not [c d]: %v", running)
+	}
+	if prog := manager.GetProgress(); prog != 50 {
+		t.Errorf("progress not 50: %v", prog)
+	}
+
+	manager.Wait()
+	if finished := manager.Filter(gotask.Finished); len(finished) != 4 {
+		t.Errorf("amount finished not 4: %v", finished)
+	}
+	if prog := manager.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+	if load := manager.GetRemainingWorkLoad(); load != 0 {
+		t.Errorf("remaining workload not 0: %v", load)
+	}
+}
+
+func TestManagerShutdown(t *testing.T) {
+
+	manager := createManager(1, 3)
+	for _, id := range manager.GetIDs() {
+		_ = manager.Start(id, 0)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := manager.Shutdown(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if canceled := manager.Filter(gotask.Canceled); len(canceled) != 1 || canceled[0] != "a" {
+		t.Errorf("canceled not [a]: %v", canceled)
+	}
+	if waiting := manager.Filter(gotask.Waiting); len(waiting) != 2 {
+		t.Errorf("amount waiting not 2: %v", waiting)
+	}
+	if queued := manager.GetQueued(); len(queued) != 0 {
+		t.Errorf("queued not empty: %v", queued)
+	}
+	if err := manager.Start("b", 0); !errors.Is(err, gotask.ErrManagerShutdown) {
+		t.Errorf("err not ErrManagerShutdown: %v", err)
+	}
+	if err := manager.Shutdown(); !errors.Is(err, gotask.ErrManagerShutdown) {
+		t.Errorf("err not ErrManagerShutdown: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// recording test middleware recording its name before and after the run of a task in log
+func recording(log *runLog, name string) gotask.Middleware {
+	return func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			_ = log.record(name + " " + h.GetTask().GetName())
+			err := next(h)
+			_ = log.record(name + " done")
+			return err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+
+	log := &runLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.Use(recording(log, "outer"), recording(log, "inner"))
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", log.Run, "task 0").Use(recording(log, "task")))
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	expected := "[outer task 0 inner task 0 task task 0 task 0 task done inner done outer done]"
+	if runs := fmtRuns(log.Runs()); runs != expected {
+		t.Errorf("unexpected order:\n%v\nexpected:\n%v", runs, expected)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+
+	log := &runLog{}
+	worker := createRunLogWorker(log, "task 0", "task 1")
+	errDenied := errors.New("denied")
+	_ = worker.Use(func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			if h.GetTask().GetName() == "task 1" {
+				return errDenied
+			}
+			return next(h)
+		}
+	})
+	_ = worker.Run(0)
+	err := worker.Wait()
+	if !errors.Is(err, errDenied) || !errors.Is(err, gotask.ErrWorkerTaskFailed) {
+		t.Errorf("expected err %v, got: %v", errDenied, err)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[task 0]" {
+		t.Errorf("run not [task 0]: %v", runs)
+	}
+	if attempts := worker.GetReport().Tasks[1].Attempts; attempts != 0 {
+		t.Errorf("attempts of short-circuited task not 0: %v", attempts)
+	}
+}
+
+func TestMiddlewareModifyError(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Failing, nil))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.Use(func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			if err := next(h); err != nil && !errors.Is(err, errDeploy) {
+				return err
+			}
+			return nil // deploy failures are tolerated
+		}
+	})
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if state := worker.GetState(); state != gotask.Finished {
+		t.Errorf("worker state not equal to %v: %v", gotask.Finished, state)
+	}
+}
+
+func TestMiddlewareSkip(t *testing.T) {
+
+	log := &runLog{}
+	worker := createRunLogWorker(log, "task 0", "task 1")
+	_ = worker.Use(func(next gotask.RunFunc) gotask.RunFunc {
+		return func(h *gotask.Handle) error {
+			if h.GetTask().GetName() == "task 1" {
+				return nil
+			}
+			return next(h)
+		}
+	})
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if progress := worker.GetProgress(); progress != gotask.MaxProgress {
+		t.Errorf("progress not equal to %v: %v", gotask.MaxProgress, progress)
+	}
+	if state := worker.GetReport().Tasks[1].State; state != gotask.Skipped {
+		t.Errorf("state of short-circuited task not equal to %v: %v", gotask.Skipped, state)
+	}
+}
+
+func TestMiddlewareSwallowedErrorNotCompensated(t *testing.T) {
+
+	log := &runLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Failing, nil).
+		SetCompensation(func(arg interface{}) error { return log.record("undo task 0") }).
+		Use(func(next gotask.RunFunc) gotask.RunFunc {
+			return func(h *gotask.Handle) error {
+				_ = next(h)
+				return nil
+			}
+		}))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "", Failing, nil))
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", errDeploy, err)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[]" {
+		t.Errorf("task whose error was swallowed was compensated: %v", runs)
+	}
+	if state := worker.GetReport().Tasks[0].State; state != gotask.Failed {
+		t.Errorf("state of task not equal to %v: %v", gotask.Failed, state)
+	}
+	if load := worker.GetTotalWorkLoad(); load != 2 {
+		t.Errorf("total workload not 2: %v", load)
+	}
+}
+
+func TestMiddlewareErrorAfterFinishedCompensated(t *testing.T) {
+
+	log := &runLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Sleeping, 1).
+		SetCompensation(func(arg interface{}) error { return log.record("undo task 0") }).
+		Use(func(next gotask.RunFunc) gotask.RunFunc {
+			return func(h *gotask.Handle) error {
+				_ = next(h)
+				return errDeploy
+			}
+		}))
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", errDeploy, err)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[undo task 0]" {
+		t.Errorf("finished task not compensated: %v", runs)
+	}
+}
+
+func TestMiddlewareRetry(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"task 0": true}}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", log.Run, "task 0").Use(gotask.Retry(3, time.Millisecond)))
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if runs := fmtRuns(log.Runs()); runs != "[task 0 task 0]" {
+		t.Errorf("run not [task 0 task 0]: %v", runs)
+	}
+	if task := worker.GetReport().Tasks[0]; task.Attempts != 2 || task.State != gotask.Finished {
+		t.Errorf("unexpected summary of retried task: %+v", task)
+	}
+
+	// all attempts fail
+	worker = gotask.NewWorker("Workername")
+	_ = worker.Use(gotask.Retry(3, time.Millisecond))
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", Failing, nil))
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", errDeploy, err)
+	}
+	if attempts := worker.GetReport().Tasks[0].Attempts; attempts != 3 {
+		t.Errorf("attempts not 3: %v", attempts)
+	}
+}
+
+func TestMiddlewareRecover(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.Use(gotask.Recover())
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", func(arg interface{}) error { panic("boom") }, nil))
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, gotask.ErrTaskPanicked) || !errors.Is(err, gotask.ErrWorkerTaskFailed) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrTaskPanicked, err)
+	}
+	task := worker.GetReport().Tasks[0]
+	if task.State != gotask.Failed || task.End.IsZero() {
+		t.Errorf("unexpected summary of panicked task: %+v", task)
+	}
+	if err := worker.Reset(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if state := worker.GetSubtasks()[0].GetState(); state != gotask.Waiting {
+		t.Errorf("task state not equal to %v: %v", gotask.Waiting, state)
+	}
+}
@@ -0,0 +1,75 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// createOptionalWorker helper function creating a worker with an optional task estimated to take one second
+func createOptionalWorker(log *runLog) *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetSkipOptional(true)
+	_ = worker.AddTask(gotask.NewTask("build", gotask.Weight(0.05), "", Sleeping, 50))
+	_ = worker.AddTask(gotask.NewTask("docs", gotask.Weight(1), "", log.Run, "docs").SetOptional(true))
+	_ = worker.AddTask(gotask.NewTask("package", gotask.Weight(0.01), "", log.Run, "package"))
+	return worker
+}
+
+func TestSkipOptional(t *testing.T) {
+
+	log := &runLog{}
+	worker := createOptionalWorker(log)
+	result, _ := worker.DryRun(500 * time.Millisecond)
+	if event := result.Events[4]; !result.Fits || event.Name != "docs" || event.State != gotask.Skipped ||
+		event.Reason != "optional task could not finish in time" {
+		t.Errorf("optional task not projected as skipped: %+v", result.Events)
+	}
+
+	_ = worker.Run(500 * time.Millisecond)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if state, progress := worker.GetState(), worker.GetProgress(); state != gotask.Finished || progress != gotask.MaxProgress {
+		t.Errorf("worker not finished: %v, %v", state, progress)
+	}
+	if runs := log.Runs(); len(runs) != 1 || runs[0] != "package" {
+		t.Errorf("run not [package]: %v", runs)
+	}
+	if report := worker.GetReport(); report.Tasks[1].State != gotask.Skipped || report.Tasks[1].Attempts != 0 ||
+		report.Tasks[1].Reason != "optional task could not finish in time" {
+		t.Errorf("optional task not reported as skipped: %+v", report.Tasks[1])
+	}
+
+	// optional tasks are run if skipping is disabled or the worker has no timeout
+	_ = worker.Reset()
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 2 {
+		t.Errorf("run not [docs, package]: %v", runs)
+	}
+}
+
+func TestSkipOptionalHistory(t *testing.T) {
+
+	log := &runLog{}
+	worker := createOptionalWorker(log)
+	docs := worker.GetSubtasks()[1]
+	if estimate := worker.GetEstimate(docs); estimate != time.Second {
+		t.Errorf("estimate of task not run yet not 1s: %v", estimate)
+	}
+
+	// once run, the measured duration is used instead of the weight
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if estimate := worker.GetEstimate(docs); estimate >= 100*time.Millisecond {
+		t.Errorf("estimate not measured duration: %v", estimate)
+	}
+	_ = worker.Reset()
+	_ = worker.Run(500 * time.Millisecond)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 4 {
+		t.Errorf("optional task not run in both runs: %v", runs)
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// Printing Writes amount lines to the output of the task with a short pause after the first line
+func Printing(h *gotask.Handle, amount interface{}) error {
+	for idx := 0; idx < amount.(int); idx++ {
+		fmt.Fprintf(h.GetOutput(), "line %d\n", idx)
+		if idx == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+func TestTaskOutput(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	printing := gotask.NewHandleTask("printing", gotask.Weight(1), "", Printing, 3)
+	failing := gotask.NewCommandTask("failing", gotask.Weight(1), "", "sh", "-c", "echo compiling; echo syntax error >&2; exit 1")
+	_ = worker.AddTasks([]gotask.Runnable{printing, failing})
+	if output := worker.GetOutput(printing); output != nil {
+		t.Errorf("output of task not run yet not nil: %v", output)
+	}
+
+	_ = worker.Run(0)
+	waitFor(t, func() bool {
+		output := worker.GetOutput(printing)
+		return output != nil && output.String() == "line 0\n"
+	})
+	_ = worker.Wait()
+
+	if output := worker.GetOutput(printing).String(); output != "line 0\nline 1\nline 2\n" {
+		t.Errorf("output not three lines: %q", output)
+	}
+	// the output of a failed command is kept for inspection, stdout and stderr may interleave in any order
+	output := worker.GetOutput(failing).String()
+	if !strings.Contains(output, "compiling\n") || !strings.Contains(output, "syntax error\n") {
+		t.Errorf("output not 'compiling, syntax error': %q", output)
+	}
+}
+
+func TestTaskOutputLimit(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetOutputLimit(14)
+	task := gotask.NewHandleTask("printing", gotask.Weight(1), "", Printing, 5)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	output := worker.GetOutput(task)
+	if data := output.String(); data != "line 3\nline 4\n" {
+		t.Errorf("output not last two lines: %q", data)
+	}
+	if dropped := output.GetDropped(); dropped != 21 {
+		t.Errorf("dropped not 21: %v", dropped)
+	}
+}
+
+func TestTaskOutputSubscribe(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	task := gotask.NewHandleTask("printing", gotask.Weight(1), "", Printing, 3)
+	_ = worker.AddTask(task)
+	chunks, cancel := worker.SubscribeOutput(10)
+	defer cancel()
+
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return worker.GetOutput(task) != nil })
+	lines, _ := worker.GetOutput(task).Subscribe(10)
+	_ = worker.Wait()
+
+	// the task subscription is closed once the task completed and only got output written after subscribing
+	var received []string
+	for line := range lines {
+		received = append(received, string(line))
+	}
+	if len(received) != 2 || received[0] != "line 1\n" {
+		t.Errorf("task subscription not [line 1, line 2]: %q", received)
+	}
+	for idx := 0; idx < 3; idx++ {
+		chunk := <-chunks
+		if chunk.Task != "printing" || string(chunk.Data) != fmt.Sprintf("line %d\n", idx) {
+			t.Errorf("chunk not 'line %d' of 'printing': %v %q", idx, chunk.Task, chunk.Data)
+		}
+	}
+}
+
+func TestTaskOutputFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetOutputDir(dir)
+	_ = worker.SetOutputLimit(7)
+	_ = worker.AddTask(gotask.NewHandleTask("build step/1", gotask.Weight(1), "", Printing, 2))
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	// the file gets the full output regardless of the limit
+	content, err := ioutil.ReadFile(filepath.Join(dir, "build_step_1.log"))
+	if err != nil || string(content) != "line 0\nline 1\n" {
+		t.Errorf("output file not two lines: %q, %v", content, err)
+	}
+}
+
+func TestTaskOutputFilesUnique(t *testing.T) {
+
+	dir := t.TempDir()
+	worker := gotask.NewWorker("Workername")
+	_ = worker.SetOutputDir(dir)
+	_ = worker.AddTask(gotask.NewHandleTask("build step/1", gotask.Weight(1), "", Printing, 1))
+	_ = worker.AddTask(gotask.NewHandleTask("build step 1", gotask.Weight(1), "", Printing, 2))
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	// both names map to the same file name, so the second task gets a suffix instead of truncating the first file
+	first, _ := ioutil.ReadFile(filepath.Join(dir, "build_step_1.log"))
+	second, _ := ioutil.ReadFile(filepath.Join(dir, "build_step_1-2.log"))
+	if string(first) != "line 0\n" || string(second) != "line 0\nline 1\n" {
+		t.Errorf("output files not separated: %q, %q", first, second)
+	}
+
+	// output is kept in memory if the file can not be created
+	worker = gotask.NewWorker("Workername")
+	_ = worker.SetOutputDir(filepath.Join(dir, "missing"))
+	task := gotask.NewHandleTask("task 0", gotask.Weight(1), "", Printing, 1)
+	_ = worker.AddTask(task)
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if output := worker.GetOutput(task); output.GetFileError() == nil || output.String() != "line 0\n" {
+		t.Errorf("file error not reported: %v", output.GetFileError())
+	}
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+// Noop task target doing nothing
+func Noop(arg interface{}) error {
+	return nil
+}
+
+// createLargeWorker helper function creating a worker with amount tiny tasks and optionally a blocking task in front
+func createLargeWorker(amount int, block *gotasktest.FakeTask) *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	tasks := make([]gotask.Runnable, 0, amount+1)
+	if block != nil {
+		tasks = append(tasks, block)
+	}
+	for idx := 0; idx < amount; idx++ {
+		tasks = append(tasks, gotask.NewTask("tiny", gotask.Weight(1), "", Noop, nil))
+	}
+	_ = worker.AddTasks(tasks)
+	return worker
+}
+
+func TestProgressWhileRunning(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	block := gotasktest.NewFakeTask("block", gotask.Weight(1))
+	_ = worker.AddTask(gotask.NewTask("first", gotask.Weight(1), "", Noop, nil))
+	_ = worker.AddTask(block)
+	_ = worker.AddTask(gotask.NewTask("last", gotask.Weight(2), "", Noop, nil))
+	if load := worker.GetTotalWorkLoad(); load != 4 {
+		t.Fatalf("total workload not 4: %v", load)
+	}
+
+	_ = worker.Run(5 * time.Second)
+	<-block.Started()
+	if prog := worker.GetProgress(); prog != 25 {
+		t.Errorf("progress not 25: %v", prog)
+	}
+	if load := worker.GetRemainingWorkLoad(); load != 3 {
+		t.Errorf("remaining workload not 3: %v", load)
+	}
+
+	block.Release()
+	_ = worker.Wait()
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+	if load := worker.GetRemainingWorkLoad(); load != 0 {
+		t.Errorf("remaining workload not 0: %v", load)
+	}
+
+	_ = worker.Reset()
+	if load := worker.GetRemainingWorkLoad(); load != 4 {
+		t.Errorf("remaining workload not 4 after reset: %v", load)
+	}
+	_ = worker.ClearTasks()
+	if load := worker.GetTotalWorkLoad(); load != 0 {
+		t.Errorf("total workload not 0 after clear: %v", load)
+	}
+}
+
+func BenchmarkGetProgress(b *testing.B) {
+
+	for _, amount := range []int{1000, 100000, 1000000} {
+		b.Run(fmt.Sprint(amount), func(b *testing.B) {
+			block := gotasktest.NewFakeTask("block", gotask.Weight(1))
+			worker := createLargeWorker(amount, block)
+			_ = worker.Run(time.Hour)
+			<-block.Started()
+			b.ResetTimer()
+			for idx := 0; idx < b.N; idx++ {
+				worker.GetProgress()
+				worker.GetRemainingWorkLoad()
+			}
+			b.StopTimer()
+			_ = worker.Stop()
+		})
+	}
+}
+
+func BenchmarkRunTinyTasks(b *testing.B) {
+
+	for _, amount := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprint(amount), func(b *testing.B) {
+			for idx := 0; idx < b.N; idx++ {
+				b.StopTimer()
+				worker := createLargeWorker(amount, nil)
+				b.StartTimer()
+				_ = worker.Run(time.Hour)
+				_ = worker.Wait()
+			}
+		})
+	}
+}
+
+func TestProgressRerunAfterTimeout(t *testing.T) {
+
+	worker := createWorker()
+	_ = worker.Run(70 * time.Millisecond)
+	_ = worker.Wait()
+	if state := worker.GetState(); state != gotask.TimeoutReached {
+		t.Fatalf("worker state not equal to %v: %v", gotask.StateToString(gotask.TimeoutReached), gotask.StateToString(state))
+	}
+
+	// all tasks are run again, so the work of the tasks finished before the timeout is not counted twice
+	_ = worker.Run(0)
+	time.Sleep(10 * time.Millisecond)
+	if prog := worker.GetProgress(); prog != 0 {
+		t.Errorf("progress not 0: %v", prog)
+	}
+	_ = worker.Wait()
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+	if remaining := worker.GetRemainingWorkLoad(); remaining != 0 {
+		t.Errorf("remaining workload not 0: %v", remaining)
+	}
+}
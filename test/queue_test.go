@@ -0,0 +1,231 @@
+package test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+func openQueue(t *testing.T, path string) *gotask.DiskQueue {
+	queue, err := gotask.OpenDiskQueue(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	return queue
+}
+
+func TestDiskQueueRestart(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	queue := openQueue(t, path)
+	for _, name := range []string{"task 0", "task 1", "task 2"} {
+		_, _ = queue.Push(gotask.TaskSpec{Name: name, Kind: "copy", Payload: []byte(name)})
+	}
+	first, _ := queue.Pop(time.Minute)
+	_ = queue.Ack(first.Receipt)
+	second, _ := queue.Pop(time.Minute)
+	_ = queue.Close()
+
+	// completed and in flight items survive the restart, the in flight item is not delivered again before its timeout
+	queue = openQueue(t, path)
+	defer queue.Close()
+	if item, _ := queue.Get(first.Spec.ID); item.State != gotask.Finished {
+		t.Errorf("item state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(item.State))
+	}
+	if item, _ := queue.Get(second.Spec.ID); item.State != gotask.Running || item.Attempts != 1 {
+		t.Errorf("item not in flight after one attempt: %v", item)
+	}
+	if amount := queue.Len(); amount != 2 {
+		t.Errorf("length not 2: %v", amount)
+	}
+	third, _ := queue.Pop(time.Minute)
+	if third == nil || third.Spec.Name != "task 2" || string(third.Spec.Payload) != "task 2" {
+		t.Errorf("popped item not 'task 2': %v", third)
+	}
+	if item, _ := queue.Pop(time.Minute); item != nil {
+		t.Errorf("popped item not nil: %v", item)
+	}
+	if id, _ := queue.Push(gotask.TaskSpec{Name: "task 3"}); id != "4" {
+		t.Errorf("id not 4: %v", id)
+	}
+}
+
+func TestDiskQueueVisibilityTimeout(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	queue := openQueue(t, filepath.Join(t.TempDir(), "queue.log"))
+	defer queue.Close()
+	queue.SetClock(clock)
+	queue.SetMaxAttempts(2)
+	id, _ := queue.Push(gotask.TaskSpec{Name: "task 0"})
+
+	first, _ := queue.Pop(time.Minute)
+	if item, _ := queue.Pop(time.Minute); item != nil {
+		t.Errorf("popped item not nil: %v", item)
+	}
+	clock.Advance(time.Minute)
+	second, _ := queue.Pop(time.Minute)
+	if second == nil || second.Spec.ID != id || second.Attempts != 2 {
+		t.Fatalf("item not delivered again: %v", second)
+	}
+
+	// the consumer of the expired delivery can not fail the item delivered again
+	if err := queue.Nack(first.Receipt, errDeploy); !errors.Is(err, gotask.ErrQueueReceiptStale) {
+		t.Errorf("err not ErrQueueReceiptStale: %v", err)
+	}
+	if item, _ := queue.Get(id); item.State != gotask.Running || item.Attempts != 2 {
+		t.Errorf("item not in flight after two attempts: %v", item)
+	}
+
+	// once the last attempt timed out as well, the item is dead
+	clock.Advance(time.Minute)
+	if item, _ := queue.Pop(time.Minute); item != nil {
+		t.Errorf("popped item not nil: %v", item)
+	}
+	if dead := queue.GetDeadLetters(); len(dead) != 1 || dead[0].LastError != "visibility timeout expired" {
+		t.Errorf("dead letters not [task 0]: %v", dead)
+	}
+	if err := queue.Ack(second.Receipt); !errors.Is(err, gotask.ErrQueueItemNotInFlight) {
+		t.Errorf("err not ErrQueueItemNotInFlight: %v", err)
+	}
+}
+
+func TestDiskQueueDeadLetter(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	queue := openQueue(t, path)
+	queue.SetMaxAttempts(2)
+	id, _ := queue.Push(gotask.TaskSpec{Name: "task 0"})
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		item, _ := queue.Pop(time.Minute)
+		if item == nil || item.Attempts != attempt {
+			t.Errorf("item not delivered with attempt %v: %v", attempt, item)
+			continue
+		}
+		_ = queue.Nack(item.Receipt, errors.New("disk full"))
+	}
+	if item, _ := queue.Pop(time.Minute); item != nil {
+		t.Errorf("popped item not nil: %v", item)
+	}
+	_ = queue.Close()
+
+	queue = openQueue(t, path)
+	defer queue.Close()
+	if dead := queue.GetDeadLetters(); len(dead) != 1 || dead[0].LastError != "disk full" {
+		t.Errorf("dead letters not [task 0]: %v", dead)
+	}
+	if err := queue.Retry("7"); !errors.Is(err, gotask.ErrQueueItemUnknown) {
+		t.Errorf("err not ErrQueueItemUnknown: %v", err)
+	}
+	if err := queue.Retry(id); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if item, _ := queue.Pop(time.Minute); item == nil || item.Attempts != 1 {
+		t.Errorf("retried item not delivered: %v", item)
+	}
+}
+
+func TestDiskQueueTornJournal(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	queue := openQueue(t, path)
+	_, _ = queue.Push(gotask.TaskSpec{Name: "task 0"})
+	_ = queue.Close()
+
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = file.WriteString(`{"op":"push","id":"2","sp`)
+	file.Close()
+
+	queue = openQueue(t, path)
+	_, _ = queue.Push(gotask.TaskSpec{Name: "task 1"})
+	_ = queue.Close()
+	queue = openQueue(t, path)
+	defer queue.Close()
+	if amount := queue.Len(); amount != 2 {
+		t.Errorf("length not 2: %v", amount)
+	}
+
+	file, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	_, _ = file.WriteString("garbage\n{\"op\":\"ack\",\"id\":\"1\"}\n")
+	file.Close()
+	if _, err := gotask.OpenDiskQueue(path); !errors.Is(err, gotask.ErrQueueCorrupt) {
+		t.Errorf("err not ErrQueueCorrupt: %v", err)
+	}
+}
+
+func TestDiskQueueCompact(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "queue.log")
+	queue := openQueue(t, path)
+	for idx := 0; idx < 20; idx++ {
+		_, _ = queue.Push(gotask.TaskSpec{Name: "task"})
+		item, _ := queue.Pop(time.Minute)
+		_ = queue.Nack(item.Receipt, errors.New("busy"))
+		item, _ = queue.Pop(time.Minute)
+		if idx%2 == 0 {
+			_ = queue.Ack(item.Receipt)
+		}
+	}
+	before, _ := os.Stat(path)
+	if err := queue.Compact(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("journal not smaller after compaction: %v >= %v", after.Size(), before.Size())
+	}
+	_, _ = queue.Push(gotask.TaskSpec{Name: "task"})
+	_ = queue.Close()
+
+	queue = openQueue(t, path)
+	defer queue.Close()
+	if finished := queue.GetItems(gotask.Finished); len(finished) != 10 {
+		t.Errorf("amount finished not 10: %v", len(finished))
+	}
+	running := queue.GetItems(gotask.Running)
+	if len(running) != 10 || running[0].Attempts != 2 || running[0].LastError != "busy" {
+		t.Errorf("in flight items not restored: %v", running)
+	}
+	if waiting := queue.GetItems(gotask.Waiting); len(waiting) != 1 || waiting[0].Spec.ID != "21" {
+		t.Errorf("waiting items not [21]: %v", waiting)
+	}
+}
+
+func TestDiskQueueTasks(t *testing.T) {
+
+	queue := openQueue(t, filepath.Join(t.TempDir(), "queue.log"))
+	defer queue.Close()
+	for _, name := range []string{"task 0", "task 1", "task 2"} {
+		_, _ = queue.Push(gotask.TaskSpec{Name: name})
+	}
+
+	tasks, err := queue.Tasks(10, time.Minute, func(spec gotask.TaskSpec) error {
+		if spec.Name == "task 1" {
+			return errDeploy
+		}
+		return nil
+	})
+	if err != nil || len(tasks) != 3 {
+		t.Errorf("tasks not created: %v, %v", len(tasks), err)
+	}
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("queue").SetErrorPolicy(gotask.ContinueOnError)
+	_ = worker.AddTasks(tasks)
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, errDeploy) {
+		t.Errorf("err not errDeploy: %v", err)
+	}
+
+	if finished := queue.GetItems(gotask.Finished); len(finished) != 2 {
+		t.Errorf("amount finished not 2: %v", len(finished))
+	}
+	if waiting := queue.GetItems(gotask.Waiting); len(waiting) != 1 || waiting[0].Spec.Name != "task 1" {
+		t.Errorf("waiting items not [task 1]: %v", waiting)
+	}
+}
@@ -0,0 +1,128 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+// helper function
+func createFastWorker(amount int) *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	for i := 0; i < amount; i++ {
+		_ = worker.AddTask(gotask.NewTask("task", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1).SetTags("api"))
+	}
+	return worker
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+
+	limiter := gotask.NewRateLimiter(10, 2)
+	if !limiter.Allow() || !limiter.Allow() {
+		t.Errorf("expected burst of 2 to be allowed")
+	}
+	if limiter.Allow() {
+		t.Errorf("expected third call to be limited")
+	}
+	time.Sleep(110 * time.Millisecond)
+	if !limiter.Allow() {
+		t.Errorf("expected token to be refilled after 100ms")
+	}
+}
+
+func TestWorkerRateLimit(t *testing.T) {
+
+	worker := createFastWorker(4)
+	_ = worker.SetRateLimiter(gotask.NewRateLimiter(20, 2))
+
+	worker.Run(0)
+	worker.Wait()
+	if state := worker.GetState(); state != gotask.Finished {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+	// two tasks start immediately, the other two wait for 50ms each
+	if dur, _ := worker.GetDuration(); dur < 0.095 || dur > 0.150 {
+		t.Errorf("duration not 0.100: %v", dur)
+	}
+}
+
+func TestWorkerTagRateLimit(t *testing.T) {
+
+	worker := createFastWorker(2)
+	_ = worker.AddTask(gotask.NewTask("untagged", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1))
+	_ = worker.SetTagRateLimiter("api", gotask.NewRateLimiter(1, 1))
+	_ = worker.SetTagRateLimiter("other", gotask.NewRateLimiter(1, 1))
+
+	worker.Run(0)
+	time.Sleep(50 * time.Millisecond)
+	if reason := worker.GetWaitingFor(); reason != "rate limit: api" {
+		t.Errorf("waiting reason not 'rate limit: api': %v", reason)
+	}
+	if name, _ := worker.GetCurrentTaskName(); name != "task" {
+		t.Errorf("current task name not 'task': %v", name)
+	}
+	if state := worker.GetSubtasks()[1].GetState(); state != gotask.Waiting {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Waiting), gotask.StateToString(state))
+	}
+	worker.Wait()
+	if reason := worker.GetWaitingFor(); reason != "" {
+		t.Errorf("waiting reason not empty: %v", reason)
+	}
+}
+
+func TestWorkerRateLimitStop(t *testing.T) {
+
+	worker := createFastWorker(3)
+	_ = worker.SetRateLimiter(gotask.NewRateLimiter(1, 1))
+
+	worker.Run(0)
+	time.Sleep(50 * time.Millisecond)
+	start := time.Now()
+	worker.Stop()
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("stop waited for rate limiter: %v", elapsed)
+	}
+	if err := worker.Wait(); err != gotask.ErrWorkerCanceledByUser {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerCanceledByUser, err)
+	}
+	if weight := worker.GetRemainingWorkLoad(); weight != 2 {
+		t.Errorf("remaining weight not equal to 2: %v", weight)
+	}
+}
+
+func TestWorkerRateLimitTimeout(t *testing.T) {
+
+	worker := createFastWorker(3)
+	_ = worker.SetRateLimiter(gotask.NewRateLimiter(1, 1))
+
+	worker.Run(50 * time.Millisecond)
+	if err := worker.Wait(); err != gotask.ErrWorkerTimeoutReached {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerTimeoutReached, err)
+	}
+	if state := worker.GetState(); state != gotask.TimeoutReached {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.TimeoutReached), gotask.StateToString(state))
+	}
+	if dur, _ := worker.GetDuration(); dur > 0.1 {
+		t.Errorf("duration not 0.050: %v", dur)
+	}
+}
+
+func TestRateLimiterFakeClock(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	worker := createFastWorker(2)
+	_ = worker.SetClock(clock)
+	_ = worker.SetRateLimiter(gotask.NewRateLimiter(1, 1).SetClock(clock))
+
+	_ = worker.Run(0)
+	gotasktest.WaitFor(t, func() bool { return worker.GetWaitingFor() == "rate limit" && clock.GetWaiters() == 1 })
+	clock.Advance(time.Second)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if duration, _ := worker.GetDuration(); duration != 1 {
+		t.Errorf("duration not 1s: %v", duration)
+	}
+}
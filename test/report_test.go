@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// createReportWorker helper function creating a worker whose second of three tasks fails
+func createReportWorker() *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "Sleeping for 20ms", Sleeping, 20))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(2), "Failing", Failing, nil))
+	_ = worker.AddTask(gotask.NewTask("task 2", gotask.Weight(3), "Sleeping for 20ms", Sleeping, 20))
+	return worker
+}
+
+func TestReport(t *testing.T) {
+
+	worker := createReportWorker()
+	if report := worker.GetReport(); report.State != gotask.Waiting || !report.Start.IsZero() || report.Duration != 0 {
+		t.Errorf("report of worker not run yet not empty: %+v", report)
+	}
+	_ = worker.Run(0)
+	_ = worker.Wait()
+
+	report := worker.GetReport()
+	if report.Worker != "Workername" || report.State != gotask.Failed || !strings.Contains(report.Error, "deploy failed") {
+		t.Errorf("unexpected report of worker: %+v", report)
+	}
+	if report.End.Before(report.Start) || report.Duration < 20*time.Millisecond {
+		t.Errorf("unexpected timing of worker: %v to %v, %v", report.Start, report.End, report.Duration)
+	}
+	if len(report.Tasks) != 3 {
+		t.Fatalf("amount of tasks not 3: %v", len(report.Tasks))
+	}
+
+	first, failed, skipped := report.Tasks[0], report.Tasks[1], report.Tasks[2]
+	if first.State != gotask.Finished || first.Attempts != 1 || first.Duration < 20*time.Millisecond || first.Weight != 1 {
+		t.Errorf("unexpected summary of finished task: %+v", first)
+	}
+	if failed.State != gotask.Failed || failed.Attempts != 1 || failed.Error != "deploy failed" || failed.Desc != "Failing" {
+		t.Errorf("unexpected summary of failed task: %+v", failed)
+	}
+	if failed.Start.Before(first.End) {
+		t.Errorf("failed task started before first task ended: %v, %v", failed.Start, first.End)
+	}
+	if skipped.State != gotask.Waiting || skipped.Attempts != 0 || !skipped.Start.IsZero() || skipped.Duration != 0 {
+		t.Errorf("unexpected summary of task not run: %+v", skipped)
+	}
+
+	// a reset clears the timings of the last run
+	_ = worker.Reset()
+	if report := worker.GetReport(); report.Tasks[0].Attempts != 0 || report.Tasks[1].Error != "" {
+		t.Errorf("report not reset: %+v", report)
+	}
+}
+
+func TestReportRender(t *testing.T) {
+
+	worker := createReportWorker()
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	report := worker.GetReport()
+
+	text := report.Text()
+	if !strings.HasPrefix(text, "Worker 'Workername' FAILED after ") || !strings.Contains(text, "TASK") {
+		t.Errorf("unexpected text report: %s", text)
+	}
+	if lines := strings.Split(strings.TrimSpace(text), "\n"); len(lines) != 6 || !strings.Contains(lines[4], "deploy failed") {
+		t.Errorf("text report not header, error, table header and three tasks: %s", text)
+	}
+
+	markdown := report.Markdown()
+	if !strings.Contains(markdown, "| task 1 | Failing | FAILED | 2 |") {
+		t.Errorf("markdown report missing row of failed task: %s", markdown)
+	}
+
+	data, err := report.JSON()
+	if err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	var decoded gotask.Report
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if decoded.Worker != report.Worker || len(decoded.Tasks) != 3 || decoded.Tasks[1].Error != "deploy failed" || decoded.Tasks[0].Duration != report.Tasks[0].Duration {
+		t.Errorf("decoded report not equal to report: %+v", decoded)
+	}
+}
+
+func TestDurationStopsAtEnd(t *testing.T) {
+
+	worker := createReportWorker()
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	time.Sleep(20 * time.Millisecond)
+
+	dur, _ := worker.GetDuration()
+	if expected := float64(worker.GetReport().Duration/time.Millisecond) / 1000; dur != expected {
+		t.Errorf("duration not equal to duration of report %v: %v", expected, dur)
+	}
+}
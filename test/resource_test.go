@@ -0,0 +1,211 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+// usageTracker Tracks maximum amount of targets running at the same time
+type usageTracker struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+// Using test function which marks tracker as used for 20ms
+func (u *usageTracker) Using(arg interface{}) error {
+	u.mu.Lock()
+	u.current++
+	if u.current > u.max {
+		u.max = u.current
+	}
+	u.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	u.mu.Lock()
+	u.current--
+	u.mu.Unlock()
+	return nil
+}
+
+func TestResourceSharedBetweenWorkers(t *testing.T) {
+
+	manager := gotask.NewResourceManager()
+	tracker := &usageTracker{}
+
+	workers := []*gotask.Worker{gotask.NewWorker("worker 0"), gotask.NewWorker("worker 1"), gotask.NewWorker("worker 2")}
+	for _, worker := range workers {
+		_ = worker.SetResourceManager(manager)
+		for i := 0; i < 3; i++ {
+			_ = worker.AddTask(gotask.NewTask("db task", gotask.Weight(1), "Using db for 20ms", tracker.Using, nil).RequireResource("db", 1))
+		}
+	}
+	for _, worker := range workers {
+		worker.Run(0)
+	}
+	for _, worker := range workers {
+		worker.Wait()
+	}
+
+	if tracker.max != 1 {
+		t.Errorf("maximum concurrent usage not 1: %v", tracker.max)
+	}
+	if used, capacity := manager.GetUsage("db"); used != 0 || capacity != 1 {
+		t.Errorf("expected usage 0 of 1, got: %v of %v", used, capacity)
+	}
+}
+
+func TestResourceCapacity(t *testing.T) {
+
+	manager := gotask.NewResourceManager()
+	manager.SetCapacity("device", 2)
+	tracker := &usageTracker{}
+
+	var workers []*gotask.Worker
+	for i := 0; i < 4; i++ {
+		worker := gotask.NewWorker("worker")
+		_ = worker.SetResourceManager(manager)
+		_ = worker.AddTask(gotask.NewTask("device task", gotask.Weight(1), "Using device for 20ms", tracker.Using, nil).RequireResource("device", 1))
+		workers = append(workers, worker)
+		worker.Run(0)
+	}
+	for _, worker := range workers {
+		worker.Wait()
+	}
+	if tracker.max != 2 {
+		t.Errorf("maximum concurrent usage not 2: %v", tracker.max)
+	}
+
+	// a task requiring more units than available gets exclusive access instead of blocking forever
+	worker := gotask.NewWorker("worker")
+	_ = worker.SetResourceManager(manager)
+	_ = worker.AddTask(gotask.NewTask("device task", gotask.Weight(1), "Using device for 1ms", Sleeping, 1).RequireResource("device", 5))
+	worker.Run(100 * time.Millisecond)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+}
+
+func TestResourceWaitingVisibility(t *testing.T) {
+
+	manager := gotask.NewResourceManager()
+	holder := gotask.NewWorker("holder")
+	_ = holder.SetResourceManager(manager)
+	_ = holder.AddTask(gotask.NewTask("holding", gotask.Weight(1), "Sleeping for 100ms", Sleeping, 100).RequireResource("db", 1).RequireResource("gpu", 1))
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	waiter := gotask.NewWorker("waiter")
+	_ = waiter.SetClock(clock)
+	_ = waiter.SetResourceManager(manager)
+	_ = waiter.AddTask(gotask.NewTask("waiting", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1).RequireResource("gpu", 1).RequireResource("db", 1))
+
+	holder.Run(0)
+	time.Sleep(10 * time.Millisecond)
+	waiter.Run(0)
+	time.Sleep(10 * time.Millisecond)
+
+	if reason := waiter.GetWaitingFor(); reason != "resource: db, gpu" {
+		t.Errorf("waiting reason not 'resource: db, gpu': %v", reason)
+	}
+	waiting := manager.GetWaiting()
+	if len(waiting) != 1 || waiting[0].Worker != "waiter" || waiting[0].Task != "waiting" || waiting[0].Resources["db"] != 1 ||
+		!waiting[0].Since.Equal(clock.Now()) {
+		t.Errorf("unexpected waiting tasks: %v", waiting)
+	}
+
+	// stopping the waiting worker withdraws its request
+	waiter.Stop()
+	if err := waiter.Wait(); err != gotask.ErrWorkerCanceledByUser {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerCanceledByUser, err)
+	}
+	if waiting := manager.GetWaiting(); len(waiting) != 0 {
+		t.Errorf("expected no waiting tasks, got: %v", waiting)
+	}
+	holder.Wait()
+	if used, _ := manager.GetUsage("db"); used != 0 {
+		t.Errorf("db still in use: %v", used)
+	}
+}
+
+func TestResourceFairness(t *testing.T) {
+
+	manager := gotask.NewResourceManager()
+	manager.SetCapacity("db", 2)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(arg interface{}) error {
+		mu.Lock()
+		order = append(order, arg.(string))
+		mu.Unlock()
+		return Sleeping(20)
+	}
+
+	// "exclusive" needs both db units and arrives before "small", so "small" must not overtake it
+	holder := gotask.NewWorker("holder")
+	_ = holder.SetResourceManager(manager)
+	_ = holder.AddTask(gotask.NewTask("holder", gotask.Weight(1), "", record, "holder").RequireResource("db", 1))
+	exclusive := gotask.NewWorker("exclusive")
+	_ = exclusive.SetResourceManager(manager)
+	_ = exclusive.AddTask(gotask.NewTask("exclusive", gotask.Weight(1), "", record, "exclusive").RequireResource("db", 2))
+	small := gotask.NewWorker("small")
+	_ = small.SetResourceManager(manager)
+	_ = small.AddTask(gotask.NewTask("small", gotask.Weight(1), "", record, "small").RequireResource("db", 1))
+
+	holder.Run(0)
+	time.Sleep(5 * time.Millisecond)
+	exclusive.Run(0)
+	time.Sleep(5 * time.Millisecond)
+	small.Run(0)
+	holder.Wait()
+	exclusive.Wait()
+	small.Wait()
+
+	if len(order) != 3 || order[1] != "exclusive" || order[2] != "small" {
+		t.Errorf("expected order [holder exclusive small], got: %v", order)
+	}
+}
+
+func TestResourceWaitingConcurrentTasks(t *testing.T) {
+
+	manager := gotask.NewResourceManager()
+	holder := gotask.NewWorker("holder")
+	_ = holder.SetResourceManager(manager)
+	_ = holder.AddTask(gotask.NewTask("holding", gotask.Weight(1), "Sleeping for 100ms", Sleeping, 100).RequireResource("db", 1).RequireResource("gpu", 1))
+
+	// both tasks of the concurrent stage wait at the same time, each with its own reason
+	db := gotask.NewTask("db task", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1).RequireResource("db", 1)
+	gpu := gotask.NewTask("gpu task", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1).RequireResource("gpu", 1)
+	waiter := gotask.NewWorker("waiter")
+	_ = waiter.SetResourceManager(manager)
+	waiter.AddStage("waiting").SetConcurrency(2)
+	_ = waiter.AddTasks([]gotask.Runnable{db, gpu})
+
+	holder.Run(0)
+	time.Sleep(10 * time.Millisecond)
+	waiter.Run(0)
+	time.Sleep(10 * time.Millisecond)
+
+	if reason := waiter.GetTaskWaitingFor(db); reason != "resource: db" {
+		t.Errorf("waiting reason not 'resource: db': %v", reason)
+	}
+	if reason := waiter.GetTaskWaitingFor(gpu); reason != "resource: gpu" {
+		t.Errorf("waiting reason not 'resource: gpu': %v", reason)
+	}
+	if tasks := waiter.Snapshot().Tasks; tasks[0].WaitingFor != "resource: db" || tasks[1].WaitingFor != "resource: gpu" {
+		t.Errorf("snapshot waiting reasons not 'resource: db' and 'resource: gpu': %+v", tasks)
+	}
+
+	holder.Wait()
+	if err := waiter.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if reason := waiter.GetTaskWaitingFor(db); reason != "" {
+		t.Errorf("waiting reason not empty: %v", reason)
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/morgadow/gotask"
+)
+
+// runLog Records names of run tasks, tasks whose name is in failing fail on their first run
+type runLog struct {
+	mu      sync.Mutex
+	runs    []string
+	failing map[string]bool
+}
+
+// Run test function which records its argument and fails once if it is in failing
+func (l *runLog) Run(arg interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	name := arg.(string)
+	l.runs = append(l.runs, name)
+	if l.failing[name] {
+		l.failing[name] = false
+		return errDeploy
+	}
+	return nil
+}
+
+// Runs Returns and clears recorded names
+func (l *runLog) Runs() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	runs := l.runs
+	l.runs = nil
+	return runs
+}
+
+// createRunLogWorker helper function creating a worker with one task per name, recorded by log
+func createRunLogWorker(log *runLog, names ...string) *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	for _, name := range names {
+		_ = worker.AddTask(gotask.NewTask(name, gotask.Weight(1), "", log.Run, name))
+	}
+	return worker
+}
+
+func TestResume(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"task 1": true}}
+	worker := createRunLogWorker(log, "task 0", "task 1", "task 2")
+	if err := worker.Resume(0); err != gotask.ErrWorkerNotStarted {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerNotStarted, err)
+	}
+	_ = worker.Run(0)
+	if err := worker.Wait(); !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", errDeploy, err)
+	}
+	log.Runs()
+
+	if err := worker.Resume(0); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if runs := log.Runs(); len(runs) != 2 || runs[0] != "task 1" || runs[1] != "task 2" {
+		t.Errorf("resumed run not [task 1, task 2]: %v", runs)
+	}
+	if state, progress := worker.GetState(), worker.GetProgress(); state != gotask.Finished || progress != gotask.MaxProgress {
+		t.Errorf("worker not finished: %v, %v", state, progress)
+	}
+	if report := worker.GetReport(); report.Tasks[0].Attempts != 1 || report.Tasks[1].Attempts != 2 {
+		t.Errorf("attempts not 1 and 2: %+v", report.Tasks)
+	}
+	if err := worker.Resume(0); err != gotask.ErrWorkerNothingToRerun {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerNothingToRerun, err)
+	}
+}
+
+func TestResumeAfterStop(t *testing.T) {
+
+	log := &runLog{}
+	worker := createRunLogWorker(log, "task 0", "task 1")
+	_ = worker.AddTask(gotask.NewTask("task 2", gotask.Weight(1), "", Sleeping, 50))
+	_ = worker.AddTask(gotask.NewTask("task 3", gotask.Weight(1), "", log.Run, "task 3"))
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return worker.GetSubtasks()[2].GetState() == gotask.Running })
+	_ = worker.Stop()
+	log.Runs()
+
+	_ = worker.Resume(0)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 1 || runs[0] != "task 3" {
+		t.Errorf("resumed run not [task 3]: %v", runs)
+	}
+}
+
+func TestRerunFailed(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"task 1": true, "task 2": true}}
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("checks").SetErrorPolicy(gotask.ContinueOnError)
+	for _, name := range []string{"task 0", "task 1", "task 2", "task 3"} {
+		_ = worker.AddTask(gotask.NewTask(name, gotask.Weight(1), "", log.Run, name))
+	}
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	log.Runs()
+
+	if err := worker.RerunFailed(0); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if runs := log.Runs(); len(runs) != 2 || runs[0] != "task 1" || runs[1] != "task 2" {
+		t.Errorf("rerun not [task 1, task 2]: %v", runs)
+	}
+	if err := worker.RerunFailed(0); err != gotask.ErrWorkerNothingToRerun {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerNothingToRerun, err)
+	}
+}
+
+func TestResumeCompensated(t *testing.T) {
+
+	log := &runLog{failing: map[string]bool{"task 1": true}}
+	undo := &compensationLog{}
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "", log.Run, "task 0").SetCompensation(undo.Undo))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "", log.Run, "task 1"))
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	log.Runs()
+
+	// the work of task 0 was undone, so it is run again
+	_ = worker.Resume(0)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 2 || runs[0] != "task 0" || runs[1] != "task 1" {
+		t.Errorf("resumed run not [task 0, task 1]: %v", runs)
+	}
+}
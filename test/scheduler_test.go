@@ -0,0 +1,217 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+	"github.com/morgadow/gotask/gotasktest"
+)
+
+// waitFor Polls condition until it is true or a second passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("condition not reached in time")
+}
+
+func TestParseCron(t *testing.T) {
+
+	start := time.Date(2022, 8, 13, 10, 30, 0, 0, time.UTC) // saturday
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2022, 8, 13, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2022, 8, 13, 10, 45, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2022, 8, 14, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2022, 8, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2022, 8, 14, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2022, 8, 31, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2022, 8, 13, 11, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2022, 8, 13, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		schedule, err := gotask.ParseCron(test.expr)
+		if err != nil {
+			t.Errorf("err not nil for '%v': %v", test.expr, err)
+			continue
+		}
+		if next := schedule.Next(start); !next.Equal(test.next) {
+			t.Errorf("next activation of '%v' not %v: %v", test.expr, test.next, next)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * * *", "5-1 * * * *", "*/0 * * * *", "@every x"} {
+		if _, err := gotask.ParseCron(expr); !errors.Is(err, gotask.ErrCronInvalid) {
+			t.Errorf("expected err %v for '%v', got: %v", gotask.ErrCronInvalid, expr, err)
+		}
+	}
+}
+
+func TestParseCronLocation(t *testing.T) {
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	schedule, _ := gotask.ParseCron("0 3 * * *")
+	next := schedule.Next(time.Date(2022, 8, 13, 0, 0, 0, 0, time.UTC).In(loc))
+	if expected := time.Date(2022, 8, 13, 1, 0, 0, 0, time.UTC); !next.Equal(expected) {
+		t.Errorf("next activation not %v: %v", expected, next)
+	}
+}
+
+func TestSchedulerInterval(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	worker := createWorker()
+	scheduler := gotask.NewWorkerScheduler("scheduler", gotask.Every(time.Hour), worker)
+	_ = scheduler.SetClock(clock)
+
+	if err := scheduler.Start(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if next := scheduler.GetNextRun(); !next.Equal(time.Date(2022, 8, 13, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("next run not 11:00: %v", next)
+	}
+
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return scheduler.GetRunCount() == 1 && scheduler.GetCurrentWorker() == nil })
+	if state := worker.GetState(); state != gotask.Finished {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return scheduler.GetRunCount() == 2 && scheduler.GetCurrentWorker() == nil })
+	if err := scheduler.GetLastError(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+
+	if err := scheduler.Stop(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if err := scheduler.Stop(); err != gotask.ErrSchedulerNotRunning {
+		t.Errorf("expected err %v, got: %v", gotask.ErrSchedulerNotRunning, err)
+	}
+}
+
+func TestSchedulerStopStartedRun(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	scheduler := gotask.NewScheduler("scheduler", gotask.Every(time.Hour), createWorker)
+	_ = scheduler.SetClock(clock)
+	_ = scheduler.Start()
+
+	// the current worker is running as soon as it is visible, so stopping does not wait for the whole run
+	clock.Advance(time.Hour)
+	waitFor(t, func() bool { return scheduler.GetCurrentWorker() != nil })
+	worker := scheduler.GetCurrentWorker()
+	_ = scheduler.Stop()
+	if state := worker.GetState(); state != gotask.Canceled {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+}
+
+func TestSchedulerFactoryUsesScheduler(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	var scheduler *gotask.Scheduler
+	names := make(chan string, 2)
+	scheduler = gotask.NewScheduler("scheduler", gotask.Every(time.Hour), func() *gotask.Worker {
+		names <- fmt.Sprintf("run %v at %v", scheduler.GetRunCount(), scheduler.GetLastRun().Format("15:04"))
+		return gotask.NewWorker("Workername")
+	})
+	_ = scheduler.SetClock(clock)
+	_ = scheduler.Start()
+	defer scheduler.Stop()
+
+	clock.Advance(time.Hour)
+	select {
+	case name := <-names:
+		if name != "run 1 at 11:00" {
+			t.Errorf("name not 'run 1 at 11:00': %v", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("factory not called or blocked")
+	}
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+
+	policies := []struct {
+		policy  gotask.OverlapPolicy
+		runs    int
+		skipped int
+	}{
+		{gotask.OverlapSkip, 1, 1},
+		{gotask.OverlapQueue, 2, 0},
+		{gotask.OverlapCancel, 2, 0},
+	}
+	for _, p := range policies {
+		clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+		scheduler := gotask.NewScheduler("scheduler", gotask.Every(time.Second), createWorker)
+		_ = scheduler.SetClock(clock)
+		_ = scheduler.SetOverlapPolicy(p.policy)
+		_ = scheduler.Start()
+
+		clock.Advance(time.Second)
+		waitFor(t, func() bool { return scheduler.GetCurrentWorker() != nil })
+		clock.Advance(time.Second) // first run is still in progress as it takes 150ms
+		waitFor(t, func() bool { return scheduler.GetRunCount() == p.runs && scheduler.GetCurrentWorker() == nil })
+
+		if skipped := scheduler.GetSkippedCount(); skipped != p.skipped {
+			t.Errorf("policy %v: skipped runs not %v: %v", p.policy, p.skipped, skipped)
+		}
+		_ = scheduler.Stop()
+	}
+}
+
+func TestSchedulerCatchUp(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	scheduler := gotask.NewScheduler("scheduler", gotask.Every(time.Hour), func() *gotask.Worker {
+		worker := gotask.NewWorker("Workername")
+		_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1))
+		return worker
+	})
+	_ = scheduler.SetClock(clock)
+	_ = scheduler.SetCatchUp(2)
+	_ = scheduler.SetLastRun(time.Date(2022, 8, 13, 5, 0, 0, 0, time.UTC)) // activations 6:00 to 10:00 missed
+
+	_ = scheduler.Start()
+	waitFor(t, func() bool { return scheduler.GetRunCount() == 3 && scheduler.GetCurrentWorker() == nil })
+	if skipped := scheduler.GetSkippedCount(); skipped != 2 {
+		t.Errorf("skipped runs not 2: %v", skipped)
+	}
+	if last := scheduler.GetLastRun(); !last.Equal(clock.Now()) {
+		t.Errorf("last run not %v: %v", clock.Now(), last)
+	}
+	if next := scheduler.GetNextRun(); !next.Equal(time.Date(2022, 8, 13, 11, 0, 0, 0, time.UTC)) {
+		t.Errorf("next run not 11:00: %v", next)
+	}
+	_ = scheduler.Stop()
+}
+
+func TestSchedulerJitter(t *testing.T) {
+
+	clock := gotasktest.NewFakeClock(time.Date(2022, 8, 13, 10, 0, 0, 0, time.UTC))
+	scheduler := gotask.NewScheduler("scheduler", gotask.Every(time.Hour), createWorker)
+	_ = scheduler.SetClock(clock)
+	_ = scheduler.SetJitter(time.Minute)
+	_ = scheduler.Start()
+
+	clock.Advance(time.Hour - time.Nanosecond)
+	time.Sleep(10 * time.Millisecond)
+	if runs := scheduler.GetRunCount(); runs != 0 {
+		t.Errorf("runs before activation not 0: %v", runs)
+	}
+	clock.Advance(time.Minute)
+	waitFor(t, func() bool { return scheduler.GetRunCount() == 1 })
+	_ = scheduler.Stop()
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"path"
+	"regexp"
+	"testing"
+
+	"github.com/morgadow/gotask"
+)
+
+// createTaggedWorker helper function creating a worker with quick and slow tasks recorded by log
+func createTaggedWorker(log *runLog) *gotask.Worker {
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewTask("lint", gotask.Weight(1), "", log.Run, "lint").SetTags("quick"))
+	_ = worker.AddTask(gotask.NewTask("unit-tests", gotask.Weight(2), "", log.Run, "unit-tests").SetTags("quick", "tests"))
+	_ = worker.AddTask(gotask.NewTask("integration-tests", gotask.Weight(10), "", log.Run, "integration-tests").SetTags("tests").SetLabel("env", "ci"))
+	_ = worker.AddTask(gotask.NewTask("package", gotask.Weight(3), "", log.Run, "package").SetLabel("env", "ci"))
+	return worker
+}
+
+func TestSelectionMatches(t *testing.T) {
+
+	task := gotask.NewTask("unit-tests", gotask.Weight(1), "", Sleeping, 1).SetTags("quick", "tests").SetLabel("env", "ci")
+	selections := []struct {
+		selection *gotask.Selection
+		matches   bool
+	}{
+		{gotask.NewSelection(), true},
+		{gotask.NewSelection().IncludeTags("slow", "quick"), true},
+		{gotask.NewSelection().IncludeTags("slow"), false},
+		{gotask.NewSelection().IncludeTags("quick").ExcludeTags("tests"), false},
+		{gotask.NewSelection().MatchNames("lint", "unit-*"), true},
+		{gotask.NewSelection().MatchNames("lint"), false},
+		{gotask.NewSelection().MatchNames("lint").MatchPattern(regexp.MustCompile("tests$")), true},
+		{gotask.NewSelection().MatchLabel("env", "ci"), true},
+		{gotask.NewSelection().MatchLabel("env", "prod"), false},
+	}
+	for idx, entry := range selections {
+		if matches := entry.selection.Matches(task); matches != entry.matches {
+			t.Errorf("selection %d matches not %v", idx, entry.matches)
+		}
+	}
+}
+
+func TestSelectiveRun(t *testing.T) {
+
+	log := &runLog{}
+	worker := createTaggedWorker(log)
+	if err := worker.SetSelection(gotask.NewSelection().MatchNames("[")); err != path.ErrBadPattern {
+		t.Errorf("expected err %v, got: %v", path.ErrBadPattern, err)
+	}
+	if err := worker.SetSelection(gotask.NewSelection().IncludeTags("quick")); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if load := worker.GetTotalWorkLoad(); load != 3 {
+		t.Errorf("total workload of selected tasks not 3: %v", load)
+	}
+
+	_ = worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if runs := log.Runs(); len(runs) != 2 || runs[0] != "lint" || runs[1] != "unit-tests" {
+		t.Errorf("run not [lint, unit-tests]: %v", runs)
+	}
+	if state, progress := worker.GetState(), worker.GetProgress(); state != gotask.Finished || progress != gotask.MaxProgress {
+		t.Errorf("worker not finished: %v, %v", state, progress)
+	}
+	report := worker.GetReport()
+	if report.Tasks[1].State != gotask.Finished || report.Tasks[2].State != gotask.Skipped || report.Tasks[3].State != gotask.Skipped {
+		t.Errorf("unselected tasks not skipped: %+v", report.Tasks)
+	}
+	if worker.IsSelected(worker.GetSubtasks()[2]) {
+		t.Errorf("unselected task reported as selected")
+	}
+
+	// a full run of the same worker
+	_ = worker.Reset()
+	_ = worker.SetSelection(nil)
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 4 {
+		t.Errorf("full run not all four tasks: %v", runs)
+	}
+}
+
+func TestSelectionByLabel(t *testing.T) {
+
+	log := &runLog{}
+	worker := createTaggedWorker(log)
+	_ = worker.SetSelection(gotask.NewSelection().MatchLabel("env", "ci").ExcludeTags("tests"))
+	_ = worker.Run(0)
+	_ = worker.Wait()
+	if runs := log.Runs(); len(runs) != 1 || runs[0] != "package" {
+		t.Errorf("run not [package]: %v", runs)
+	}
+	if snapshot := worker.Snapshot(); snapshot.TotalWorkLoad != 3 || snapshot.Tasks[0].State != gotask.Skipped {
+		t.Errorf("unexpected snapshot: %+v", snapshot)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+func TestStateText(t *testing.T) {
+
+	states := []gotask.State{gotask.Waiting, gotask.Running, gotask.Canceled, gotask.Finished, gotask.TimeoutReached, gotask.Failed, gotask.Skipped}
+	for _, state := range states {
+		text, err := state.MarshalText()
+		if err != nil {
+			t.Errorf("err not nil: %v", err)
+		}
+		var decoded gotask.State
+		if err := decoded.UnmarshalText(text); err != nil || decoded != state {
+			t.Errorf("state %v not round tripped: %v, %v", state, decoded, err)
+		}
+		if gotask.StringToState(gotask.StateToString(state)) != state {
+			t.Errorf("state %v not round tripped by StringToState", state)
+		}
+	}
+	if name := gotask.Canceled.String(); name != "CANCELED" {
+		t.Errorf("name not 'CANCELED': %v", name)
+	}
+
+	var state gotask.State
+	if err := state.UnmarshalText([]byte("DONE")); !errors.Is(err, gotask.ErrStateUnknown) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStateUnknown, err)
+	}
+	if _, err := gotask.State(42).MarshalText(); !errors.Is(err, gotask.ErrStateUnknown) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStateUnknown, err)
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+
+	data, err := json.Marshal(map[string]gotask.State{"state": gotask.TimeoutReached})
+	if err != nil || string(data) != `{"state":"TIMEOUT"}` {
+		t.Errorf("unexpected json: %s, %v", data, err)
+	}
+	var decoded struct{ State gotask.State }
+	if err := json.Unmarshal([]byte(`{"State":"FAILED"}`), &decoded); err != nil || decoded.State != gotask.Failed {
+		t.Errorf("state not decoded to FAILED: %v, %v", decoded.State, err)
+	}
+	if err := json.Unmarshal([]byte(`{"State":"DONE"}`), &decoded); !errors.Is(err, gotask.ErrStateUnknown) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStateUnknown, err)
+	}
+	// numbers were written by earlier versions
+	if err := json.Unmarshal([]byte(`{"State":2}`), &decoded); err != nil || decoded.State != gotask.Canceled {
+		t.Errorf("state not decoded to CANCELED: %v, %v", decoded.State, err)
+	}
+	if err := json.Unmarshal([]byte(`{"State":42}`), &decoded); !errors.Is(err, gotask.ErrStateUnknown) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStateUnknown, err)
+	}
+}
+
+func TestWorkerSnapshot(t *testing.T) {
+
+	worker := createWorker()
+	_ = worker.Run(0)
+	waitFor(t, func() bool { return worker.GetSubtasks()[1].GetState() == gotask.Running })
+
+	snapshot := worker.Snapshot()
+	if snapshot.Name != "Workername" || snapshot.State != gotask.Running || snapshot.Start.IsZero() || !snapshot.End.IsZero() {
+		t.Errorf("unexpected snapshot of running worker: %+v", snapshot)
+	}
+	if len(snapshot.Tasks) != 3 || snapshot.Tasks[0].State != gotask.Finished || snapshot.Tasks[1].Attempts != 1 || snapshot.Tasks[2].Attempts != 0 {
+		t.Errorf("unexpected task snapshots: %+v", snapshot.Tasks)
+	}
+	_ = worker.Wait()
+
+	data, err := json.Marshal(worker.Snapshot())
+	if err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	var decoded gotask.WorkerSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("err not nil: %v", err)
+	}
+	if decoded.State != gotask.Finished || decoded.Progress != gotask.MaxProgress || decoded.Tasks[2].State != gotask.Finished || decoded.Tasks[2].Weight != 3 {
+		t.Errorf("unexpected decoded snapshot: %+v", decoded)
+	}
+}
+
+func TestWorkerSnapshotConsistent(t *testing.T) {
+
+	worker := createWorker()
+	_ = worker.Run(10 * time.Second)
+	waitFor(t, func() bool { return worker.GetSubtasks()[1].GetState() == gotask.Running })
+
+	snapshot := worker.Snapshot()
+	if snapshot.CurrentTask != "task 1" || snapshot.Tasks[1].State != gotask.Running || snapshot.CurrentStage != snapshot.Tasks[1].Stage {
+		t.Errorf("current task and stage not matching task states: %+v", snapshot)
+	}
+	if snapshot.Timeout.IsZero() || snapshot.RemainingTime <= 0 || snapshot.RemainingTime > 10*time.Second {
+		t.Errorf("unexpected remaining time: %v", snapshot.RemainingTime)
+	}
+	if !snapshot.Start.Add(snapshot.Duration).Equal(snapshot.Time) || snapshot.Timeout.Sub(snapshot.Time) != snapshot.RemainingTime {
+		t.Errorf("timings not taken at snapshot time: %+v", snapshot)
+	}
+	if snapshot.TotalWorkLoad != 6 || snapshot.RemainingWorkLoad != 5 || snapshot.Progress < 16 || snapshot.Progress > 17 {
+		t.Errorf("workloads not matching task progress: %v, %v, %v", snapshot.TotalWorkLoad, snapshot.RemainingWorkLoad, snapshot.Progress)
+	}
+	if len(snapshot.Stages) != 1 || snapshot.Stages[0].State != gotask.Running || snapshot.Stages[0].Tasks != 3 {
+		t.Errorf("unexpected stage snapshots: %+v", snapshot.Stages)
+	}
+
+	// changing a snapshot does not affect the worker or later snapshots
+	snapshot.Tasks[0].Name = "changed"
+	_ = worker.Wait()
+	snapshot = worker.Snapshot()
+	if snapshot.Tasks[0].Name != "task 0" || snapshot.CurrentTask != "" || snapshot.RemainingTime != 0 {
+		t.Errorf("unexpected snapshot of finished worker: %+v", snapshot)
+	}
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+func TestStagesRunInOrder(t *testing.T) {
+
+	tracker := &usageTracker{}
+	worker := gotask.NewWorker("Workername")
+	prepare := worker.AddStage("prepare").SetConcurrency(3)
+	_ = worker.AddTask(gotask.NewTask("prepare 0", gotask.Weight(1), "", tracker.Using, nil))
+	_ = worker.AddTask(gotask.NewTask("prepare 1", gotask.Weight(1), "", tracker.Using, nil))
+	_ = worker.AddTask(gotask.NewTask("prepare 2", gotask.Weight(1), "", tracker.Using, nil))
+	build := worker.AddStage("build")
+	_ = build.AddTask(gotask.NewTask("build", gotask.Weight(3), "", tracker.Using, nil))
+
+	// tasks added to an earlier stage are inserted behind its tasks in the queue
+	_ = prepare.AddTask(gotask.NewTask("prepare 3", gotask.Weight(1), "", tracker.Using, nil))
+	if name := worker.GetSubtasks()[4].GetName(); name != "build" {
+		t.Errorf("last task not 'build': %v", name)
+	}
+
+	worker.Run(0)
+	time.Sleep(10 * time.Millisecond)
+	if stage, _ := worker.GetCurrentStageName(); stage != "prepare" {
+		t.Errorf("current stage not 'prepare': %v", stage)
+	}
+	if state := build.GetState(); state != gotask.Waiting {
+		t.Errorf("stage state not equal to %v: %v", gotask.StateToString(gotask.Waiting), gotask.StateToString(state))
+	}
+	time.Sleep(45 * time.Millisecond) // prepare 3 runs after the first three prepare tasks
+	if stage, _ := worker.GetCurrentStageName(); stage != "build" {
+		t.Errorf("current stage not 'build': %v", stage)
+	}
+	if prog := prepare.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("stage progress not %v: %v", gotask.MaxProgress, prog)
+	}
+	if prog := worker.GetProgress(); prog < 57.1 || prog > 57.2 {
+		t.Errorf("progress not 57.1: %v", prog)
+	}
+
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if tracker.max != 3 {
+		t.Errorf("maximum concurrent tasks not 3: %v", tracker.max)
+	}
+	if dur, _ := worker.GetDuration(); dur < 0.060 || dur > 0.080 {
+		t.Errorf("duration not 0.060: %v", dur)
+	}
+	if state := prepare.GetState(); state != gotask.Finished {
+		t.Errorf("stage state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+}
+
+func TestStageTimeout(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	slow := worker.AddStage("slow").SetTimeout(25 * time.Millisecond)
+	_ = worker.AddTask(gotask.NewTask("task 0", gotask.Weight(1), "Sleeping for 15ms", Sleeping, 15))
+	_ = worker.AddTask(gotask.NewTask("task 1", gotask.Weight(1), "Sleeping for 15ms", Sleeping, 15))
+	_ = worker.AddTask(gotask.NewTask("task 2", gotask.Weight(1), "Sleeping for 15ms", Sleeping, 15))
+	next := worker.AddStage("next")
+	_ = worker.AddTask(gotask.NewTask("task 3", gotask.Weight(1), "Sleeping for 15ms", Sleeping, 15))
+
+	worker.Run(time.Second)
+	err := worker.Wait()
+	if !errors.Is(err, gotask.ErrStageTimeoutReached) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStageTimeoutReached, err)
+	}
+	if state := worker.GetState(); state != gotask.TimeoutReached {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.TimeoutReached), gotask.StateToString(state))
+	}
+	if state := slow.GetState(); state != gotask.TimeoutReached {
+		t.Errorf("stage state not equal to %v: %v", gotask.StateToString(gotask.TimeoutReached), gotask.StateToString(state))
+	}
+	if state := next.GetState(); state != gotask.Waiting {
+		t.Errorf("stage state not equal to %v: %v", gotask.StateToString(gotask.Waiting), gotask.StateToString(state))
+	}
+	if weight := worker.GetRemainingWorkLoad(); weight != 2 {
+		t.Errorf("remaining weight not equal to 2: %v", weight)
+	}
+}
+
+func TestStageErrorPolicies(t *testing.T) {
+
+	// continue on error runs all tasks of the stage and fails afterwards with all errors
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("verify").SetErrorPolicy(gotask.ContinueOnError)
+	_ = worker.AddTask(gotask.NewTask("verify 0", gotask.Weight(1), "", Failing, nil))
+	_ = worker.AddTask(gotask.NewTask("verify 1", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.AddTask(gotask.NewTask("verify 2", gotask.Weight(1), "", Failing, nil))
+	worker.AddStage("publish")
+	_ = worker.AddTask(gotask.NewTask("publish", gotask.Weight(1), "", Sleeping, 1))
+
+	worker.Run(0)
+	err := worker.Wait()
+	var stageErr *gotask.StageError
+	if !errors.As(err, &stageErr) || len(stageErr.Errors) != 2 || stageErr.Stage != "verify" {
+		t.Errorf("expected stage error with 2 errors, got: %v", err)
+	}
+	if !errors.Is(err, gotask.ErrWorkerTaskFailed) || !errors.Is(err, errDeploy) {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerTaskFailed, err)
+	}
+	subTasks := worker.GetSubtasks()
+	if subTasks[1].GetState() != gotask.Finished || subTasks[3].GetState() != gotask.Waiting {
+		t.Errorf("unexpected task states: %v, %v", gotask.StateToString(subTasks[1].GetState()), gotask.StateToString(subTasks[3].GetState()))
+	}
+
+	// ignore errors continues with the next stage
+	worker = gotask.NewWorker("Workername")
+	worker.AddStage("optional").SetErrorPolicy(gotask.IgnoreErrors)
+	_ = worker.AddTask(gotask.NewTask("optional", gotask.Weight(1), "", Failing, nil))
+	worker.AddStage("required")
+	_ = worker.AddTask(gotask.NewTask("required", gotask.Weight(1), "", Sleeping, 1))
+
+	worker.Run(0)
+	if err := worker.Wait(); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if state := worker.GetSubtasks()[1].GetState(); state != gotask.Finished {
+		t.Errorf("task state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+}
+
+func TestStageStopWithConcurrency(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	worker.AddStage("parallel").SetConcurrency(2)
+	for i := 0; i < 6; i++ {
+		_ = worker.AddTask(gotask.NewTask("task", gotask.Weight(1), "Sleeping for 40ms", Sleeping, 40))
+	}
+
+	worker.Run(0)
+	time.Sleep(60 * time.Millisecond)
+	worker.Stop()
+	if state := worker.GetState(); state != gotask.Canceled {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Canceled), gotask.StateToString(state))
+	}
+	// the first two tasks finished, the next two were running while stopping and were completed
+	if weight := worker.GetRemainingWorkLoad(); weight != 2 {
+		t.Errorf("remaining weight not equal to 2: %v", weight)
+	}
+}
+
+func TestHandleAddsToOwnStage(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	crawl := worker.AddStage("crawl")
+	_ = worker.AddTask(gotask.NewHandleTask("spawner", gotask.Weight(1), "Spawning 2 subtasks", Spawning, 2))
+	worker.AddStage("report")
+	_ = worker.AddTask(gotask.NewTask("report", gotask.Weight(1), "Sleeping for 1ms", Sleeping, 1))
+
+	worker.Run(0)
+	worker.Wait()
+	if amount := len(crawl.GetSubtasks()); amount != 3 {
+		t.Errorf("amount of stage tasks not 3: %v", amount)
+	}
+	if name := worker.GetSubtasks()[3].GetName(); name != "report" {
+		t.Errorf("last task not 'report': %v", name)
+	}
+}
+
+func TestAddToFinishedStage(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	prepare := worker.AddStage("prepare")
+	_ = prepare.AddTask(gotask.NewTask("prepare", gotask.Weight(1), "", Sleeping, 1))
+	_ = worker.AddStage("build").AddTask(gotask.NewTask("build", gotask.Weight(1), "", Sleeping, 50))
+	_ = worker.Run(0)
+	time.Sleep(20 * time.Millisecond)
+	if err := prepare.AddTask(gotask.NewTask("late", gotask.Weight(1), "", Sleeping, 1)); err != gotask.ErrStageFinished {
+		t.Errorf("expected err %v, got: %v", gotask.ErrStageFinished, err)
+	}
+	_ = worker.Wait()
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v: %v", gotask.MaxProgress, prog)
+	}
+
+	// tasks can be added to completed stages of a worker which is not running
+	if err := prepare.AddTask(gotask.NewTask("late", gotask.Weight(1), "", Sleeping, 1)); err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/morgadow/gotask"
+)
+
+// Spawning test function which adds the given amount of sleeping subtasks to the running worker
+func Spawning(h *gotask.Handle, amount interface{}) error {
+	for i := 0; i < amount.(int); i++ {
+		err := h.AddTask(gotask.NewTask(fmt.Sprintf("subtask %d", i), gotask.Weight(1), "Sleeping for 10ms", Sleeping, 10))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAddTaskWhileRunning(t *testing.T) {
+
+	worker := createWorker()
+	worker.Run(0)
+
+	err := worker.AddTask(gotask.NewTask("task 3", gotask.Weight(4), "Sleeping for 10ms", Sleeping, 10))
+	if err != nil {
+		t.Errorf("err not nil: %v", err)
+	}
+	if amountTask := worker.GetAmountSubtasks(); amountTask != 4 {
+		t.Errorf("Amount of subtasks not equal to 4: %v", amountTask)
+	}
+	if weight := worker.GetTotalWorkLoad(); weight != 10 {
+		t.Errorf("total weight not equal to 10: %v", weight)
+	}
+
+	worker.Wait()
+	if state := worker.GetState(); state != gotask.Finished {
+		t.Errorf("worker state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+	if state := worker.GetSubtasks()[3].GetState(); state != gotask.Finished {
+		t.Errorf("appended task state not equal to %v: %v", gotask.StateToString(gotask.Finished), gotask.StateToString(state))
+	}
+	if prog := worker.GetProgress(); prog != gotask.MaxProgress {
+		t.Errorf("progress not %v, got: %v", gotask.MaxProgress, prog)
+	}
+}
+
+func TestClearTasksWhileRunning(t *testing.T) {
+
+	worker := createWorker()
+	worker.Run(0)
+	if err := worker.ClearTasks(); err != gotask.ErrWorkerRunning {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerRunning, err)
+	}
+	worker.Wait()
+}
+
+func TestHandleTaskSpawnsSubtasks(t *testing.T) {
+
+	worker := gotask.NewWorker("Workername")
+	_ = worker.AddTask(gotask.NewHandleTask("spawner", gotask.Weight(1), "Spawning 3 subtasks", Spawning, 3))
+
+	worker.Run(0)
+	time.Sleep(5 * time.Millisecond) // spawner done, first subtask running
+	if amountTask := worker.GetAmountSubtasks(); amountTask != 4 {
+		t.Errorf("Amount of subtasks not equal to 4: %v", amountTask)
+	}
+	if prog := worker.GetProgress(); prog != 25 {
+		t.Errorf("progress not %v, got: %v", 25, prog)
+	}
+
+	worker.Wait()
+	if weight := worker.GetTotalWorkLoad(); weight != 4 {
+		t.Errorf("total weight not equal to 4: %v", weight)
+	}
+	if weight := worker.GetRemainingWorkLoad(); weight != 0 {
+		t.Errorf("remaining weight not equal to 0: %v", weight)
+	}
+	for _, task := range worker.GetSubtasks() {
+		if task.GetState() != gotask.Finished {
+			t.Errorf("task %v state not equal to %v: %v", task.GetName(), gotask.StateToString(gotask.Finished), gotask.StateToString(task.GetState()))
+		}
+	}
+}
+
+func TestHandleTaskWithoutWorker(t *testing.T) {
+
+	var err error
+	task := gotask.NewHandleTask("spawner", gotask.Weight(1), "Spawning 1 subtask", func(h *gotask.Handle, arg interface{}) error {
+		err = Spawning(h, arg)
+		return err
+	}, 1)
+	task.Run()
+	if err != gotask.ErrWorkerNotBound {
+		t.Errorf("expected err %v, got: %v", gotask.ErrWorkerNotBound, err)
+	}
+}
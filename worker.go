@@ -2,34 +2,93 @@ package gotask
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 )
 
 var (
 	ErrWorkerRunning        error = errors.New("worker already running, can not change taskqueue")
+	ErrWorkerNotBound       error = errors.New("task is not bound to any worker")
 	ErrWorkerNotStarted     error = errors.New("worker was not started and is still in waiting state")
 	ErrWorkerNotRunning     error = errors.New("worker is not running")
 	ErrWorkerTaskQueueEmpty error = errors.New("worker task queue is empty")
 	ErrWokerFinished        error = errors.New("worker already finished")
 	ErrWorkerTimeoutReached error = errors.New("worker reached timeout limit")
 	ErrWorkerCanceledByUser error = errors.New("worker was canceled by user")
+	ErrWorkerTaskFailed     error = errors.New("worker task failed")
 )
 
+// TaskError Error of a worker run which ended due to a failed task
+type TaskError struct {
+	Task string // name of failed task
+	Err  error  // error returned by task target
+}
+
+// Error Returns error message containing task name and task error
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("%v '%s': %v", ErrWorkerTaskFailed, e.Task, e.Err)
+}
+
+// Unwrap Returns error returned by task target
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// Is Reports TaskError to match ErrWorkerTaskFailed
+func (e *TaskError) Is(target error) bool {
+	return target == ErrWorkerTaskFailed
+}
+
 // Worker Main handler struct containing all tasks and handling their run with progress evaluation
 type Worker struct {
+	mu             sync.Mutex // guards all fields below against concurrent access from run loop, tasks and getters
 	name           string
 	state          State
+	finishing      bool // run loop still compensates or runs finally tasks after the final state was set
 	progress       Progress
 	taskQueue      []Runnable
-	currSubTask    Runnable
-	currSubTaskIdx int
+	currSubTask    Runnable // most recently started task
+	currStage      *Stage
+	stages         []*Stage  // stages in run order, the task queue holds the tasks of all stages in the same order
 	startTime      time.Time // time the worker was started
+	endTime        time.Time // time the last run ended, zero while running
 	timeoutTime    time.Time // time the timeout will be reached, if no timeout set, this is not set
 	timeoutSet     bool
 	wg             sync.WaitGroup // waitgroup so main routine can wait until worker is finished, used by Wait()
-	quit           chan (bool)    // channel to hold quit information, this will stop running next task in line
+	quit           chan struct{}  // closed to quit, this will stop running next task in line
+	done           chan struct{}  // closed by run loop once it left, so Stop does not block on a finished worker
 	err            error          // return error for wait method
+	limiter        *RateLimiter   // limits task starts of whole worker, nil if not limited
+	tagLimiters    map[string]*RateLimiter
+	waitingFor     map[Runnable]string // reasons tasks wait for before they are started, only holding waiting tasks
+	resources      *ResourceManager
+	finishedTasks  []Runnable               // successfully finished tasks in order of their run, compensated in reverse order
+	compensations  []CompensationResult     // outcomes of compensations run after last failed, stopped or timed out run
+	outputLimit    int                      // bytes kept of the output of every task, zero for DefaultOutputLimit
+	outputDir      string                   // directory output of tasks is teed to, empty if not teed
+	outputs        map[Runnable]*Output     // output of present or last run of every task
+	outputFiles    map[Runnable]string      // name of the file output of every task is teed to
+	outputNames    map[string]struct{}      // file names assigned to tasks
+	stream         *outputStream            // passes output of all tasks to subscribers
+	runs           map[Runnable]*taskRun    // timing of present or last run of every task
+	kept           map[Runnable]struct{}    // tasks kept from the last run which are not run again by a resumed run
+	selection      *Selection               // selects tasks which are run, nil if all tasks are run
+	skipped        map[Runnable]struct{}    // tasks not matching the selection
+	skipOptional   bool                     // skip optional tasks which can not finish before the timeout
+	unfit          map[Runnable]struct{}    // optional tasks skipped in present or last run as they could not finish in time
+	bypassed       map[Runnable]struct{}    // tasks skipped in present or last run as middleware did not run them
+	history        map[string]time.Duration // duration of last successful run per task name
+	beforeAll      func() error
+	afterAll       func() error
+	beforeEach     func(task Runnable) error
+	afterEach      func(task Runnable) error
+	finally        []Runnable    // tasks always run at the end of a run
+	finallyTimeout time.Duration // time every finally task has to complete, zero for DefaultFinallyTimeout
+	hookErrors     []*HookError  // errors of after hooks and finally tasks of present or last run
+	middleware     []Middleware  // wrapped around the run of every task, first one is the outermost
+	clock          Clock         // source of time for timings and timeouts
+	book           workBook      // work of selected tasks, updated on task transitions
 }
 
 // NewWorker Factory method for creating a new worker for proper initialition
@@ -39,6 +98,7 @@ func NewWorker(name string) *Worker {
 		state:    Waiting,
 		progress: MinProgress,
 		wg:       sync.WaitGroup{},
+		clock:    SystemClock,
 	}
 	return &worker
 }
@@ -46,100 +106,213 @@ func NewWorker(name string) *Worker {
 // AddTask Starts running all tasks
 // timeout Timeout in seconds which will stop worker if reached. If not set greater zero, no timeout is set.
 func (w *Worker) Run(timeout time.Duration) error {
-	if w.state == Running {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.busy() {
 		return ErrWorkerRunning
 	}
-	if w.state == Finished || w.state == Canceled {
+	if w.state == Finished || w.state == Canceled || w.state == Failed {
 		return ErrWokerFinished
 	}
-	if w.GetAmountSubtasks() == 0 {
+	if len(w.taskQueue) == 0 {
 		return nil
 	}
 
-	// runtime and deadline evaluation
 	w.err = nil
+	w.finishedTasks = nil
+	w.compensations = nil
+	w.kept = nil
+	w.start(timeout)
+	return nil
+}
+
+// start Starts run loop with timeout, caller must hold the worker lock
+func (w *Worker) start(timeout time.Duration) {
+	w.selectTasks(w.taskQueue)
+	w.unfit = nil
+	w.bypassed = nil
+	w.hookErrors = nil
+	w.recount(true)
+
+	// runtime and deadline evaluation
 	w.state = Running
-	w.startTime = time.Now()
+	w.startTime = w.clock.Now()
+	w.endTime = time.Time{}
 	if timeout > 0 {
 		w.timeoutSet = true
 		w.timeoutTime = w.startTime.Add(timeout)
 	} else {
 		w.timeoutSet = false
 	}
-	w.quit = make(chan bool)
+	w.quit = make(chan struct{})
+	w.done = make(chan struct{})
 
 	// create channel to store state in and
 	w.wg.Add(1)
 	go w.runInternal()
-
-	return nil
 }
 
 // Wait Wait until worker is finished
+// Note: If the worker already finished, the result of the last run is returned immediately
 func (w *Worker) Wait() error {
-	if w.state != Running {
+	if w.GetState() == Waiting {
 		return ErrWorkerNotRunning
 	}
 	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.err
 }
 
-// Stop Stops task run
+// Stop Stops task run, no more tasks are started and the call returns once running tasks completed
+// Note: Must not be called from inside a task target of the same worker
 func (w *Worker) Stop() error {
+	w.mu.Lock()
 	if w.state != Running {
+		w.mu.Unlock()
 		return ErrWorkerNotRunning
 	}
-	w.quit <- true
-	w.err = ErrWorkerCanceledByUser
+	select {
+	case <-w.quit:
+	default:
+		close(w.quit)
+	}
+	done := w.done
+	w.mu.Unlock()
+
+	<-done
 	return nil
 }
 
 // Reset Can be used to reset worker to status quo state to run again after run once
 func (w *Worker) Reset() error {
-	if w.state == Running {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.busy() {
 		return ErrWorkerRunning
 	}
 	w.state = Waiting
 	w.progress = MinProgress
 	w.err = nil
+	w.finishedTasks = nil
+	w.compensations = nil
+	w.runs = nil
+	w.kept = nil
+	w.unfit = nil
+	w.bypassed = nil
+	w.hookErrors = nil
+	w.currStage = nil
+	for _, stage := range w.stages {
+		stage.state = Waiting
+	}
 	for _, task := range w.taskQueue {
 		task.Reset()
 	}
+	w.recount(false)
 	return nil
 }
 
-// AddTask Adds new task to queue
+// AddTask Adds new task to queue, the task belongs to the last added stage
+// Note: Tasks can also be added to a running worker, they are appended to the end of the queue and run after all tasks already queued
 func (w *Worker) AddTask(task Runnable) error {
-	if w.state == Running {
-		return ErrWorkerRunning
-	}
-	w.taskQueue = append(w.taskQueue, task)
-	return nil
+	return w.AddTasks([]Runnable{task})
 }
 
-// AddTask Adds multiple new tasks to queue
+// AddTask Adds multiple new tasks to queue, the tasks belong to the last added stage
+// Returns ErrStageFinished if the worker is running and its last stage already completed
 func (w *Worker) AddTasks(tasks []Runnable) error {
-	var err error = nil
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stage := w.lastStage()
+	if w.state == Running && stage.completed() {
+		return ErrStageFinished
+	}
+	if w.state != Running {
+		w.selectTasks(tasks)
+	}
 	for _, task := range tasks {
-		err = w.AddTask(task)
-		if err != nil {
-			return err
+		if !w.isSkipped(task) {
+			w.book.add(task)
 		}
 	}
-	return err
+	stage.tasks = append(stage.tasks, tasks...)
+	w.taskQueue = append(w.taskQueue, tasks...)
+	return nil
 }
 
 // AddTask Emptys task queue
 func (w *Worker) ClearTasks() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.state == Running {
 		return ErrWorkerRunning
 	}
 	w.taskQueue = nil
+	for _, stage := range w.stages {
+		stage.tasks = nil
+	}
+	w.book = workBook{}
+	return nil
+}
+
+// SetClock Sets clock used for timings and timeouts, default is SystemClock
+// Note: Rate limiters refill their bucket on their own clock, see RateLimiter.SetClock
+func (w *Worker) SetClock(clock Clock) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.clock = clock
+	return nil
+}
+
+// SetRateLimiter Limits task starts of worker, the limiter can be shared with other workers. Set nil to disable.
+func (w *Worker) SetRateLimiter(limiter *RateLimiter) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.limiter = limiter
+	return nil
+}
+
+// SetTagRateLimiter Limits starts of all tasks carrying tag, the limiter can be shared with other workers. Set nil to disable.
+// Note: A task must satisfy the worker limiter and the limiters of all its tags before it is started
+func (w *Worker) SetTagRateLimiter(tag string, limiter *RateLimiter) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	if limiter == nil {
+		delete(w.tagLimiters, tag)
+		return nil
+	}
+	if w.tagLimiters == nil {
+		w.tagLimiters = make(map[string]*RateLimiter)
+	}
+	w.tagLimiters[tag] = limiter
+	return nil
+}
+
+// SetResourceManager Sets manager the resources required by tasks are acquired from, set nil to ignore task resources
+// Note: The same manager should be shared by all workers whose tasks must not use the same resources at once
+func (w *Worker) SetResourceManager(manager *ResourceManager) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.state == Running {
+		return ErrWorkerRunning
+	}
+	w.resources = manager
 	return nil
 }
 
 // GetAmountSubtasks Returns amount of tasks in queue
 func (w *Worker) GetAmountSubtasks() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return len(w.taskQueue)
 }
 
@@ -150,11 +323,15 @@ func (w *Worker) GetName() string {
 
 // GetState Returns present worker state
 func (w *Worker) GetState() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	return w.state
 }
 
 // GetProgress Returns present queue progress in percent from 0 to 100
 func (w *Worker) GetProgress() Progress {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.state == Running {
 		w.updateProgress()
 	}
@@ -163,69 +340,86 @@ func (w *Worker) GetProgress() Progress {
 
 // GetTotalWorkLoad Returns total workload of all tasks in queue combined (progress times weight)
 func (w *Worker) GetTotalWorkLoad() float64 {
-	totalLoad := 0.0
-	for _, task := range w.taskQueue {
-		totalLoad += float64(task.GetWeight())
-	}
-	return totalLoad
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.book.total
 }
 
 // GetRemainingWorkLoad Returns remaining workload of all tasks in queue combined (progress times weight)
 func (w *Worker) GetRemainingWorkLoad() float64 {
-	remainLoad := 0.0
-	for _, task := range w.taskQueue {
-		remainLoad += (1 - float64(task.GetProgress())/float64(MaxProgress)) * float64(task.GetWeight())
-	}
-	return remainLoad
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.book.total - w.book.workDone()
 }
 
 // GetDuration Get duration for how long worker was or is running in seconds
 // Note: Not to be called on worker in Waiting state
 func (w *Worker) GetDuration() (float64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.state == Waiting {
 		return 0, ErrWorkerNotStarted
 	}
-	return float64(time.Since(w.startTime)/time.Millisecond) / 1000, nil
+	return float64(durationBetween(w.startTime, w.endTime, w.clock.Now())/time.Millisecond) / 1000, nil
 }
 
 // GetDuration Get duration for how long worker was or is running in seconds
 // Note: Only to be called during running worker. If no timeout set, a -1 is returned
 func (w *Worker) GetRemainingTime() (float64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.remainingTime()
+}
+
+// remainingTime Unlocked version of GetRemainingTime, caller must hold the worker lock
+func (w *Worker) remainingTime() (float64, error) {
 	if w.state != Running {
 		return 0, ErrWorkerNotRunning
 	}
 	if !w.timeoutSet {
 		return -1, nil
 	}
-	return float64(time.Until(w.timeoutTime)/time.Millisecond) / 1000, nil
+	return float64(w.timeoutTime.Sub(w.clock.Now())/time.Millisecond) / 1000, nil
+}
+
+// busy Checks if a run is in progress, which includes compensations and finally tasks run after the final state was
+// set, caller must hold the worker lock
+func (w *Worker) busy() bool {
+	return w.state == Running || w.finishing
 }
 
 // IsReady ReConvienince function to check if worker is ready to start
 func (w *Worker) IsReady() bool {
-	return w.state == Waiting
+	return w.GetState() == Waiting
 }
 
 // IsRunning ReConvienince function to check if worker currently running
 func (w *Worker) IsRunning() bool {
-	return w.state == Running
+	return w.GetState() == Running
 }
 
 // IsFinished ReConvienince function to check if worker finished its run
 func (w *Worker) IsFinished() bool {
-	return w.state == Finished
+	return w.GetState() == Finished
 }
 
 // GetSubtasks Returns copy of all subtasks as slice
 func (w *Worker) GetSubtasks() []Runnable {
-	return w.taskQueue
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	tasks := make([]Runnable, len(w.taskQueue))
+	copy(tasks, w.taskQueue)
+	return tasks
 }
 
 // GetCurrentTaskName Returns name of presently running task
 func (w *Worker) GetCurrentTaskName() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.state != Running || w.currSubTask == nil {
 		return "", ErrWorkerNotRunning
 	}
-	if w.GetAmountSubtasks() == 0 {
+	if len(w.taskQueue) == 0 {
 		return "", ErrWorkerTaskQueueEmpty
 	}
 	return w.currSubTask.GetName(), nil
@@ -233,61 +427,164 @@ func (w *Worker) GetCurrentTaskName() (string, error) {
 
 // GetCurrentTaskDesc Returns description of presently running task
 func (w *Worker) GetCurrentTaskDesc() (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
 	if w.state != Running || w.currSubTask == nil {
 		return "", ErrWorkerNotRunning
 	}
-	if w.GetAmountSubtasks() == 0 {
+	if len(w.taskQueue) == 0 {
 		return "", ErrWorkerTaskQueueEmpty
 	}
 	return w.currSubTask.GetDesc(), nil
 }
 
-// updateProgress Updates internal progress over all tasks
+// GetWaitingFor Returns what the current task waits for before it is started, e.g. "rate limit" or "resource: db",
+// empty if not waiting. Use GetTaskWaitingFor for other tasks of a stage running tasks concurrently.
+func (w *Worker) GetWaitingFor() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.waitingFor[w.currSubTask]
+}
+
+// GetTaskWaitingFor Returns what task waits for before it is started, empty if not waiting
+func (w *Worker) GetTaskWaitingFor(task Runnable) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.waitingFor[task]
+}
+
+// setWaitingFor Sets what task waits for before it is started, an empty reason marks the task as not waiting
+func (w *Worker) setWaitingFor(task Runnable, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if reason == "" {
+		delete(w.waitingFor, task)
+		return
+	}
+	if w.waitingFor == nil {
+		w.waitingFor = make(map[Runnable]string)
+	}
+	w.waitingFor[task] = reason
+}
+
+// updateProgress Updates internal progress over all tasks, caller must hold the worker lock
 func (w *Worker) updateProgress() {
-	workTotal := 0
-	workDone := 0
-	for _, task := range w.taskQueue {
-		workTotal += int(task.GetWeight())
-		workDone += int(task.GetProgress()/100) * int(task.GetWeight())
+	if w.book.total == 0 {
+		return
 	}
-	w.progress = (Progress(workDone) / Progress(workTotal)) * 100 // multiply by 100 for percent
+	w.progress = Progress(w.book.workDone()/w.book.total) * 100 // multiply by 100 for percent
 }
 
 // runInternal Internal run function which is run in another context to handle timeout and termination
 func (w *Worker) runInternal() {
-	w.state = Running
-out:
-	for idx, task := range w.taskQueue {
-		select {
-		case <-w.quit:
-			w.state = Canceled
-			w.err = ErrWorkerCanceledByUser
-			break out
-		default:
-			w.updateProgress()
-
-			if w.timeoutReached() {
-				w.state = TimeoutReached
-				w.err = ErrWorkerTimeoutReached
-				break out
-			}
-
-			// call next subtask
-			w.currSubTaskIdx = idx
-			w.currSubTask = task
-			w.currSubTask.Run()
-
-			if w.currSubTaskIdx == len(w.taskQueue)-1 {
-				w.state = Finished
-				w.updateProgress()
-			}
+	defer w.wg.Done()
+	defer close(w.done)
+
+	state, err := Failed, w.runBeforeAll()
+	if err == nil {
+		state, err = w.runQueue()
+	}
+	w.runAfterAll()
+	w.finish(state, err)
+	if err != nil {
+		w.compensate()
+	}
+	w.runFinally()
+
+	w.mu.Lock()
+	w.finishing = false
+	w.mu.Unlock()
+}
+
+// runQueue Runs all stages one after another and returns final state and error of the run
+func (w *Worker) runQueue() (State, error) {
+	for idx := 0; ; idx++ {
+		w.mu.Lock()
+		if idx >= len(w.stages) {
+			w.mu.Unlock()
+			return Finished, nil
+		}
+		stage := w.stages[idx]
+		w.mu.Unlock()
+
+		if err := w.runStage(stage); err != nil {
+			return stateForError(err), err
 		}
 	}
-	w.wg.Done()
 }
 
-// timeoutReached Checks if timeout of worker was reached
-func (w *Worker) timeoutReached() bool {
-	remain, _ := w.GetRemainingTime()
-	return w.timeoutSet && remain < 0
+// runTask Waits for rate limiters and resources of task and runs it afterwards
+// Returns ErrWorkerCanceledByUser or the deadline error of the scope if the worker was stopped or timed out while
+// waiting, a TaskError if the task failed
+func (w *Worker) runTask(task Runnable, scope runScope) error {
+	if err := w.throttle(task, scope); err != nil {
+		return err
+	}
+	lease, err := w.acquire(task, scope)
+	if err != nil {
+		return err
+	}
+
+	if err := w.runBeforeEach(task); err != nil {
+		if lease != nil {
+			w.resources.release(lease)
+		}
+		return err
+	}
+	output := w.newTaskOutput(task)
+	handle := &Handle{worker: w, task: task, stage: scope.stage, signal: scope.signal, output: output}
+	if b, ok := task.(bindable); ok {
+		b.bind(handle)
+	}
+	w.mu.Lock()
+	w.book.start(task)
+	w.mu.Unlock()
+	reached := false
+	err = w.chain(task, &reached)(handle)
+	// a finished task is compensated even if a middleware failed it afterwards, as its work was done
+	finished := task.GetState() == Finished
+	w.mu.Lock()
+	if err == nil && !reached {
+		w.bypass(task)
+	} else {
+		w.book.end(task)
+	}
+	if finished {
+		w.finishedTasks = append(w.finishedTasks, task)
+	}
+	w.mu.Unlock()
+	output.close()
+	w.runAfterEach(task)
+
+	if lease != nil {
+		w.resources.release(lease)
+	}
+	if err != nil {
+		return &TaskError{Task: task.GetName(), Err: err}
+	}
+	return nil
+}
+
+// finish Leaves run with given state and error, the worker stays busy until compensations and finally tasks were run
+func (w *Worker) finish(state State, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+	w.finishing = true
+	w.err = err
+	w.endTime = w.clock.Now()
+	w.waitingFor = nil
+	w.updateProgress()
+}
+
+// stateForError Returns worker state matching the error a run ended with
+func stateForError(err error) State {
+	switch {
+	case errors.Is(err, ErrWorkerCanceledByUser):
+		return Canceled
+	case errors.Is(err, ErrWorkerTimeoutReached), errors.Is(err, ErrStageTimeoutReached):
+		return TimeoutReached
+	default:
+		return Failed
+	}
 }